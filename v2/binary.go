@@ -0,0 +1,453 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// CBORDurationTag is the CBOR tag used to encode durations.  The tag
+	// content is a map with the keys 1 (seconds) and -9 (nanoseconds), as
+	// defined in RFC 9581.
+	CBORDurationTag = 1002
+	// CBORTimeTag is the CBOR tag used to encode times: an RFC 3339 string,
+	// with nanosecond precision.
+	CBORTimeTag = 0
+	// MsgPackDurationExt is the MessagePack extension type used to encode
+	// durations.  The data is the duration in nanoseconds, as a big-endian int64.
+	MsgPackDurationExt = 1
+	// MsgPackTimestampExt is the MessagePack extension type used to encode
+	// times, as defined by the MessagePack spec.
+	MsgPackTimestampExt = -1
+)
+
+// ErrFrameTooLarge is returned by the binary handlers if an encoded record
+// is larger than MaxBinaryFrameSize.  The record isn't written.
+var ErrFrameTooLarge = errors.New("encoded record exceeds maximum frame size")
+
+// MaxBinaryFrameSize is the largest record the binary handlers will write, and the
+// largest frame the binlog package will read.
+const MaxBinaryFrameSize = 16 << 20
+
+// NewCBORHandler returns a slog.Handler which encodes each record as a CBOR
+// map, written as a frame prefixed by the length of the map in bytes, as a
+// big-endian uint32.  opts may be nil.
+//
+// The map's keys are the record's built-in fields (time, level, source, msg),
+// followed by its attrs, in the order they were added.  Groups are encoded as
+// nested maps.  Times and durations are tagged with CBORTimeTag and CBORDurationTag.
+// Levels are encoded as strings, like "INFO".  Other values which have no native
+// CBOR representation are encoded the way encoding/json would marshal them.
+// Like the json-pretty handler, duplicate keys are merged, and the last value wins.
+//
+// The binlog package decodes these frames back into slog.Records.
+func NewCBORHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	return newObjectHandler(w, opts, func(buf *bytes.Buffer, _ slog.Record, obj *attrObject) error {
+		return encodeBinaryFrame(buf, cborEncoder{}, obj)
+	})
+}
+
+// NewMsgPackHandler returns a slog.Handler which encodes each record as a
+// MessagePack map, written as a frame prefixed by the length of the map in
+// bytes, as a big-endian uint32.  opts may be nil.
+//
+// The layout of the map is the same as NewCBORHandler's.  Times are encoded
+// with the MessagePack timestamp extension type, and durations with
+// MsgPackDurationExt.
+//
+// The binlog package decodes these frames back into slog.Records.
+func NewMsgPackHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	return newObjectHandler(w, opts, func(buf *bytes.Buffer, _ slog.Record, obj *attrObject) error {
+		return encodeBinaryFrame(buf, msgPackEncoder{}, obj)
+	})
+}
+
+// binaryEncoder appends the primitive types of a binary encoding to a buffer.
+type binaryEncoder interface {
+	appendNil(buf *bytes.Buffer)
+	appendBool(buf *bytes.Buffer, b bool)
+	appendInt(buf *bytes.Buffer, i int64)
+	appendUint(buf *bytes.Buffer, u uint64)
+	appendFloat(buf *bytes.Buffer, f float64)
+	appendString(buf *bytes.Buffer, s string)
+	appendBytes(buf *bytes.Buffer, b []byte)
+	appendTime(buf *bytes.Buffer, t time.Time)
+	appendDuration(buf *bytes.Buffer, d time.Duration)
+	appendArrayHeader(buf *bytes.Buffer, n int)
+	appendMapHeader(buf *bytes.Buffer, n int)
+}
+
+func encodeBinaryFrame(buf *bytes.Buffer, enc binaryEncoder, obj *attrObject) error {
+	// reserve room for the length prefix
+	buf.Write([]byte{0, 0, 0, 0})
+
+	encodeBinaryObject(buf, enc, obj)
+
+	size := buf.Len() - 4
+	if size > MaxBinaryFrameSize {
+		return fmt.Errorf("%w: %d bytes exceeds the maximum of %d", ErrFrameTooLarge, size, MaxBinaryFrameSize)
+	}
+
+	binary.BigEndian.PutUint32(buf.Bytes(), uint32(size))
+
+	return nil
+}
+
+func encodeBinaryObject(buf *bytes.Buffer, enc binaryEncoder, obj *attrObject) {
+	enc.appendMapHeader(buf, len(obj.keys))
+
+	for _, k := range obj.keys {
+		enc.appendString(buf, k)
+
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			encodeBinaryObject(buf, enc, v)
+		case slog.Value:
+			encodeBinaryValue(buf, enc, v)
+		}
+	}
+}
+
+func encodeBinaryValue(buf *bytes.Buffer, enc binaryEncoder, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		enc.appendString(buf, v.String())
+	case slog.KindInt64:
+		enc.appendInt(buf, v.Int64())
+	case slog.KindUint64:
+		enc.appendUint(buf, v.Uint64())
+	case slog.KindFloat64:
+		enc.appendFloat(buf, v.Float64())
+	case slog.KindBool:
+		enc.appendBool(buf, v.Bool())
+	case slog.KindDuration:
+		enc.appendDuration(buf, v.Duration())
+	case slog.KindTime:
+		enc.appendTime(buf, v.Time())
+	default:
+		encodeBinaryAny(buf, enc, v.Any())
+	}
+}
+
+func encodeBinaryAny(buf *bytes.Buffer, enc binaryEncoder, a any) {
+	switch a := a.(type) {
+	case nil:
+		enc.appendNil(buf)
+		return
+	case slog.Level:
+		enc.appendString(buf, a.String())
+		return
+	case *slog.Source:
+		enc.appendMapHeader(buf, 3)
+		enc.appendString(buf, "function")
+		enc.appendString(buf, a.Function)
+		enc.appendString(buf, "file")
+		enc.appendString(buf, a.File)
+		enc.appendString(buf, "line")
+		enc.appendInt(buf, int64(a.Line))
+
+		return
+	case []byte:
+		enc.appendBytes(buf, a)
+		return
+	case json.Marshaler:
+	case error:
+		enc.appendString(buf, a.Error())
+		return
+	}
+
+	// fall back on encoding the value like encoding/json would
+	b, err := json.Marshal(a)
+	if err != nil {
+		enc.appendString(buf, fmt.Sprintf("!ERROR:%v", err))
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var generic any
+
+	err = dec.Decode(&generic)
+	if err != nil {
+		enc.appendString(buf, fmt.Sprintf("!ERROR:%v", err))
+		return
+	}
+
+	encodeBinaryGeneric(buf, enc, generic)
+}
+
+// encodeBinaryGeneric encodes values produced by decoding json into an any.
+func encodeBinaryGeneric(buf *bytes.Buffer, enc binaryEncoder, v any) {
+	switch v := v.(type) {
+	case nil:
+		enc.appendNil(buf)
+	case bool:
+		enc.appendBool(buf, v)
+	case string:
+		enc.appendString(buf, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			enc.appendInt(buf, i)
+		} else if f, err := v.Float64(); err == nil {
+			enc.appendFloat(buf, f)
+		} else {
+			enc.appendString(buf, v.String())
+		}
+	case []any:
+		enc.appendArrayHeader(buf, len(v))
+
+		for _, e := range v {
+			encodeBinaryGeneric(buf, enc, e)
+		}
+	case map[string]any:
+		enc.appendMapHeader(buf, len(v))
+
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			enc.appendString(buf, k)
+			encodeBinaryGeneric(buf, enc, v[k])
+		}
+	default:
+		enc.appendString(buf, fmt.Sprint(v))
+	}
+}
+
+// cborEncoder implements RFC 8949.
+type cborEncoder struct{}
+
+const (
+	cborMajorUint   = 0
+	cborMajorNegInt = 1
+	cborMajorBytes  = 2
+	cborMajorString = 3
+	cborMajorArray  = 4
+	cborMajorMap    = 5
+	cborMajorTag    = 6
+)
+
+func (cborEncoder) appendHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (cborEncoder) appendNil(buf *bytes.Buffer) {
+	buf.WriteByte(0xf6)
+}
+
+func (cborEncoder) appendBool(buf *bytes.Buffer, b bool) {
+	if b {
+		buf.WriteByte(0xf5)
+	} else {
+		buf.WriteByte(0xf4)
+	}
+}
+
+func (e cborEncoder) appendInt(buf *bytes.Buffer, i int64) {
+	if i < 0 {
+		e.appendHead(buf, cborMajorNegInt, uint64(-(i + 1)))
+		return
+	}
+
+	e.appendHead(buf, cborMajorUint, uint64(i))
+}
+
+func (e cborEncoder) appendUint(buf *bytes.Buffer, u uint64) {
+	e.appendHead(buf, cborMajorUint, u)
+}
+
+func (cborEncoder) appendFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xfb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (e cborEncoder) appendString(buf *bytes.Buffer, s string) {
+	e.appendHead(buf, cborMajorString, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func (e cborEncoder) appendBytes(buf *bytes.Buffer, b []byte) {
+	e.appendHead(buf, cborMajorBytes, uint64(len(b)))
+	buf.Write(b)
+}
+
+func (e cborEncoder) appendTime(buf *bytes.Buffer, t time.Time) {
+	e.appendHead(buf, cborMajorTag, CBORTimeTag)
+	e.appendString(buf, t.Format(time.RFC3339Nano))
+}
+
+func (e cborEncoder) appendDuration(buf *bytes.Buffer, d time.Duration) {
+	e.appendHead(buf, cborMajorTag, CBORDurationTag)
+	e.appendMapHeader(buf, 2)
+	e.appendInt(buf, 1)
+	e.appendInt(buf, int64(d/time.Second))
+	e.appendInt(buf, -9)
+	e.appendInt(buf, int64(d%time.Second))
+}
+
+func (e cborEncoder) appendArrayHeader(buf *bytes.Buffer, n int) {
+	e.appendHead(buf, cborMajorArray, uint64(n))
+}
+
+func (e cborEncoder) appendMapHeader(buf *bytes.Buffer, n int) {
+	e.appendHead(buf, cborMajorMap, uint64(n))
+}
+
+// msgPackEncoder implements https://github.com/msgpack/msgpack/blob/master/spec.md
+type msgPackEncoder struct{}
+
+func (msgPackEncoder) appendNil(buf *bytes.Buffer) {
+	buf.WriteByte(0xc0)
+}
+
+func (msgPackEncoder) appendBool(buf *bytes.Buffer, b bool) {
+	if b {
+		buf.WriteByte(0xc3)
+	} else {
+		buf.WriteByte(0xc2)
+	}
+}
+
+func (e msgPackEncoder) appendInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0:
+		e.appendUint(buf, uint64(i))
+	case i >= -32:
+		// negative fixint
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func (msgPackEncoder) appendUint(buf *bytes.Buffer, u uint64) {
+	switch {
+	case u <= math.MaxInt8:
+		// positive fixint
+		buf.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(u)))
+	case u <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(u)))
+	default:
+		buf.WriteByte(0xcf)
+		buf.Write(binary.BigEndian.AppendUint64(nil, u))
+	}
+}
+
+func (msgPackEncoder) appendFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xcb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (msgPackEncoder) appendString(buf *bytes.Buffer, s string) {
+	n := len(s)
+
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xd9, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdb)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n))) //nolint:gosec
+	}
+
+	buf.WriteString(s)
+}
+
+func (msgPackEncoder) appendBytes(buf *bytes.Buffer, b []byte) {
+	n := len(b)
+
+	switch {
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xc4, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xc5)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xc6)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n))) //nolint:gosec
+	}
+
+	buf.Write(b)
+}
+
+func (msgPackEncoder) appendTime(buf *bytes.Buffer, t time.Time) {
+	// timestamp 96: ext 8, with a 4 byte nanoseconds field and an 8 byte seconds field.
+	// 0xff is MsgPackTimestampExt as an int8.
+	buf.Write([]byte{0xc7, 12, 0xff})
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(t.Nanosecond()))) //nolint:gosec
+	buf.Write(binary.BigEndian.AppendUint64(nil, uint64(t.Unix())))       //nolint:gosec
+}
+
+func (msgPackEncoder) appendDuration(buf *bytes.Buffer, d time.Duration) {
+	// fixext 8
+	buf.Write([]byte{0xd7, MsgPackDurationExt})
+	buf.Write(binary.BigEndian.AppendUint64(nil, uint64(d))) //nolint:gosec
+}
+
+func (msgPackEncoder) appendArrayHeader(buf *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xdc)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdd)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n))) //nolint:gosec
+	}
+}
+
+func (msgPackEncoder) appendMapHeader(buf *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xde)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdf)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n))) //nolint:gosec
+	}
+}
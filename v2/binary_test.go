@@ -0,0 +1,81 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryHandlers_frameTooLarge(t *testing.T) {
+	for _, newHandler := range []func(io.Writer, *slog.HandlerOptions) slog.Handler{NewCBORHandler, NewMsgPackHandler} {
+		buf := bytes.NewBuffer(nil)
+
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", 0)
+		rec.Add("big", strings.Repeat("x", MaxBinaryFrameSize))
+
+		err := newHandler(buf, nil).Handle(context.Background(), rec)
+		require.ErrorIs(t, err, ErrFrameTooLarge)
+		assert.Zero(t, buf.Len(), "should not write the frame")
+
+		rec = slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", 0)
+		rec.Add("big", strings.Repeat("x", MaxBinaryFrameSize-100))
+
+		require.NoError(t, newHandler(buf, nil).Handle(context.Background(), rec))
+	}
+}
+
+func TestBinaryHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		newHandler func(buf *bytes.Buffer) slog.Handler
+		want       []byte
+	}{
+		{
+			name: "cbor",
+			newHandler: func(buf *bytes.Buffer) slog.Handler {
+				return NewCBORHandler(buf, nil)
+			},
+			want: []byte{
+				0, 0, 0, 33, // length prefix
+				0xa4,                                                    // map(4)
+				0x65, 'l', 'e', 'v', 'e', 'l', 0x64, 'I', 'N', 'F', 'O', // "level": "INFO"
+				0x63, 'm', 's', 'g', 0x62, 'h', 'i', // "msg": "hi"
+				0x61, 'n', 0x38, 0x63, // "n": -100
+				0x61, 'd', 0xd9, 0x03, 0xea, 0xa2, 0x01, 0x01, 0x28, 0x00, // "d": 1002({1: 1, -9: 0})
+			},
+		},
+		{
+			name: "msgpack",
+			newHandler: func(buf *bytes.Buffer) slog.Handler {
+				return NewMsgPackHandler(buf, nil)
+			},
+			want: []byte{
+				0, 0, 0, 35, // length prefix
+				0x84,                                                    // fixmap(4)
+				0xa5, 'l', 'e', 'v', 'e', 'l', 0xa4, 'I', 'N', 'F', 'O', // "level": "INFO"
+				0xa3, 'm', 's', 'g', 0xa2, 'h', 'i', // "msg": "hi"
+				0xa1, 'n', 0xd0, 0x9c, // "n": -100
+				0xa1, 'd', 0xd7, 0x01, 0, 0, 0, 0, 0x3b, 0x9a, 0xca, 0x00, // "d": ext(1, 1e9)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+
+			rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", 0)
+			rec.Add("n", -100, "d", time.Second)
+
+			require.NoError(t, tt.newHandler(buf).Handle(context.Background(), rec))
+			assert.Equal(t, tt.want, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,324 @@
+// Package binlog decodes the length-prefixed CBOR and MessagePack frames written
+// by flume's "cbor" and "msgpack" handlers (see flume.NewCBORHandler and
+// flume.NewMsgPackHandler) back into slog.Records, or into JSON for inspection.
+//
+//	dec, err := binlog.NewDecoder(os.Stdin, flume.CBORHandler)
+//	...
+//	for {
+//	    rec, err := dec.Decode()
+//	    if errors.Is(err, io.EOF) {
+//	        break
+//	    }
+//	    ...
+//	}
+//
+// Or, to convert a stream to JSON lines:
+//
+//	err := binlog.ToJSON(os.Stdout, os.Stdin, flume.MsgPackHandler)
+package binlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown binary log format")
+	ErrInvalidFrame  = errors.New("invalid frame")
+)
+
+// MaxFrameSize is the largest payload a Decoder will read, which is the largest the
+// handlers will write.  The length prefix of a corrupt stream could be anything, so
+// larger frames are rejected as invalid, rather than allocating a buffer for them.
+const MaxFrameSize = flume.MaxBinaryFrameSize
+
+// maxDepth limits how deeply maps, arrays, and tags may be nested in a frame, so a
+// corrupt frame can't overflow the stack.
+const maxDepth = 128
+
+// valueDecoder decodes a single value from a frame's payload.
+type valueDecoder interface {
+	decodeValue(p *payload) (slog.Value, error)
+}
+
+// Decoder reads frames from a stream, and decodes them into slog.Records.
+type Decoder struct {
+	r   *bufio.Reader
+	dec valueDecoder
+}
+
+// NewDecoder returns a Decoder which reads frames from r.  format must be
+// either flume.CBORHandler or flume.MsgPackHandler.
+func NewDecoder(r io.Reader, format string) (*Decoder, error) {
+	var dec valueDecoder
+
+	switch format {
+	case flume.CBORHandler:
+		dec = cborDecoder{}
+	case flume.MsgPackHandler:
+		dec = msgPackDecoder{}
+	default:
+		return nil, fmt.Errorf("%w: '%v'", ErrUnknownFormat, format)
+	}
+
+	return &Decoder{r: bufio.NewReader(r), dec: dec}, nil
+}
+
+// DecodeValue reads the next frame, and returns the decoded map as a slog.KindGroup
+// value.  The order of the members of the group matches the order of the keys in
+// the encoded map.
+//
+// Returns io.EOF if there are no more frames, and ErrInvalidFrame if the frame is
+// malformed, larger than MaxFrameSize, or nested too deeply.
+func (d *Decoder) DecodeValue() (slog.Value, error) {
+	var lenPrefix [4]byte
+
+	_, err := io.ReadFull(d.r, lenPrefix[:])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return slog.Value{}, fmt.Errorf("%w: truncated length prefix", ErrInvalidFrame)
+		}
+
+		return slog.Value{}, err //nolint:wrapcheck // io.EOF must not be wrapped
+	}
+
+	n := binary.BigEndian.Uint32(lenPrefix[:])
+	if n > MaxFrameSize {
+		return slog.Value{}, fmt.Errorf("%w: length %d exceeds the maximum of %d", ErrInvalidFrame, n, MaxFrameSize)
+	}
+
+	b := make([]byte, n)
+
+	_, err = io.ReadFull(d.r, b)
+	if err != nil {
+		return slog.Value{}, fmt.Errorf("%w: truncated payload: %w", ErrInvalidFrame, err)
+	}
+
+	p := &payload{b: b}
+
+	v, err := d.dec.decodeValue(p)
+	if err != nil {
+		return slog.Value{}, fmt.Errorf("%w: %w", ErrInvalidFrame, err)
+	}
+
+	if v.Kind() != slog.KindGroup {
+		return slog.Value{}, fmt.Errorf("%w: payload is %v, not a map", ErrInvalidFrame, v.Kind())
+	}
+
+	if p.off != len(p.b) {
+		return slog.Value{}, fmt.Errorf("%w: %d trailing bytes", ErrInvalidFrame, len(p.b)-p.off)
+	}
+
+	return v, nil
+}
+
+// Decode reads the next frame, and converts it into a slog.Record.  The time, level
+// and msg keys are converted to the record's built-in fields, if they are of the
+// expected type.  The remaining keys, including source, are added to the record as attrs.
+//
+// Returns io.EOF if there are no more frames.
+func (d *Decoder) Decode() (slog.Record, error) {
+	v, err := d.DecodeValue()
+	if err != nil {
+		return slog.Record{}, err
+	}
+
+	var rec slog.Record
+
+	rec.Level = slog.LevelInfo
+
+	for _, a := range v.Group() {
+		switch {
+		case a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime:
+			rec.Time = a.Value.Time()
+		case a.Key == slog.MessageKey && a.Value.Kind() == slog.KindString:
+			rec.Message = a.Value.String()
+		case a.Key == slog.LevelKey && a.Value.Kind() == slog.KindString && rec.Level.UnmarshalText([]byte(a.Value.String())) == nil:
+		default:
+			rec.AddAttrs(a)
+		}
+	}
+
+	return rec, nil
+}
+
+// Replay decodes each frame from r, and passes the resulting records to h.Handle, until
+// the end of the stream.
+func Replay(ctx context.Context, h slog.Handler, r io.Reader, format string) error {
+	dec, err := NewDecoder(r, format)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rec, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = h.Handle(ctx, rec)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+}
+
+// ToJSON decodes each frame from src, and writes it to dst as a line of json, using
+// slog.JSONHandler.
+func ToJSON(dst io.Writer, src io.Reader, format string) error {
+	return Replay(context.Background(), slog.NewJSONHandler(dst, &slog.HandlerOptions{Level: flume.LevelAll}), src, format)
+}
+
+// payload is a cursor over a frame's bytes.
+type payload struct {
+	b   []byte
+	off int
+	// nesting depth of the value being decoded
+	depth int
+}
+
+var (
+	errTruncated   = errors.New("unexpected end of payload")
+	errMalformed   = errors.New("malformed")
+	errUnsupported = errors.New("unsupported")
+	errTooDeep     = errors.New("nested too deeply")
+)
+
+// enter is called before decoding the contents of a map, array, or tag, and returns
+// an error if they are nested too deeply.  leave must be called after.
+func (p *payload) enter() error {
+	if p.depth >= maxDepth {
+		return fmt.Errorf("%w: more than %d levels", errTooDeep, maxDepth)
+	}
+
+	p.depth++
+
+	return nil
+}
+
+func (p *payload) leave() {
+	p.depth--
+}
+
+func (p *payload) next(n uint64) ([]byte, error) {
+	if n > uint64(len(p.b)-p.off) {
+		return nil, errTruncated
+	}
+
+	b := p.b[p.off : p.off+int(n)] //nolint:gosec // bounds checked above
+	p.off += int(n)                //nolint:gosec
+
+	return b, nil
+}
+
+func (p *payload) byte() (byte, error) {
+	b, err := p.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+func (p *payload) uint(size int) (uint64, error) {
+	b, err := p.next(uint64(size)) //nolint:gosec
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// decodeKeyedValues decodes n key/value pairs into attrs.  Keys must be strings.
+func decodeKeyedValues(p *payload, dec valueDecoder, n uint64) (slog.Value, error) {
+	err := p.enter()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	defer p.leave()
+
+	attrs := make([]slog.Attr, 0, min(n, 64))
+
+	for range n {
+		k, err := dec.decodeValue(p)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		if k.Kind() != slog.KindString {
+			return slog.Value{}, fmt.Errorf("%w: map key is %v, not a string", errMalformed, k.Kind())
+		}
+
+		v, err := dec.decodeValue(p)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		attrs = append(attrs, slog.Attr{Key: k.String(), Value: v})
+	}
+
+	return slog.GroupValue(attrs...), nil
+}
+
+// decodeArray decodes n values into a []any.  slog has no array kind, so the elements
+// are converted to plain go values.
+func decodeArray(p *payload, dec valueDecoder, n uint64) (slog.Value, error) {
+	err := p.enter()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	defer p.leave()
+
+	arr := make([]any, 0, min(n, 64))
+
+	for range n {
+		v, err := dec.decodeValue(p)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		arr = append(arr, plain(v))
+	}
+
+	return slog.AnyValue(arr), nil
+}
+
+// plain converts a value to the go value it holds.  Groups are converted to
+// map[string]any.
+func plain(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+
+	m := make(map[string]any, len(v.Group()))
+	for _, a := range v.Group() {
+		m[a.Key] = plain(a.Value)
+	}
+
+	return m
+}
+
+func durationValue(secs, nanos int64) slog.Value {
+	return slog.DurationValue(time.Duration(secs)*time.Second + time.Duration(nanos))
+}
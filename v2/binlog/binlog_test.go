@@ -0,0 +1,203 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesGroup/flume/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var formats = map[string]func(io.Writer, *slog.HandlerOptions) slog.Handler{
+	flume.CBORHandler:    flume.NewCBORHandler,
+	flume.MsgPackHandler: flume.NewMsgPackHandler,
+}
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 20, 41, 28, 515123456, time.UTC)
+
+	for format, newHandler := range formats {
+		t.Run(format, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := newHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+			l := slog.New(h).With(flume.LoggerKey, "edge").WithGroup("req")
+
+			l.Debug("first",
+				"str", strings.Repeat("x", 300),
+				"neg", -1000,
+				"negbig", int64(math.MinInt64),
+				"big", uint64(math.MaxUint64),
+				"float", 1.5,
+				"bool", true,
+				"dur", -1500*time.Millisecond,
+				"time", ts,
+				"err", errors.New("boom"),
+				"bytes", []byte{1, 2, 3},
+				"nil", nil,
+				"slice", []int{1, 2},
+				slog.Group("headers", "accept", "*/*"),
+			)
+			l.Warn("second")
+
+			dec, err := NewDecoder(buf, format)
+			require.NoError(t, err)
+
+			rec, err := dec.Decode()
+			require.NoError(t, err)
+
+			assert.Equal(t, "first", rec.Message)
+			assert.Equal(t, slog.LevelDebug, rec.Level)
+			assert.False(t, rec.Time.IsZero())
+
+			attrs := map[string]slog.Value{}
+			rec.Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a.Value
+				return true
+			})
+
+			assert.Equal(t, "edge", attrs[flume.LoggerKey].String())
+
+			req := map[string]slog.Value{}
+			for _, a := range attrs["req"].Group() {
+				req[a.Key] = a.Value
+			}
+
+			assert.Equal(t, strings.Repeat("x", 300), req["str"].String())
+			assert.Equal(t, int64(-1000), req["neg"].Int64())
+			assert.Equal(t, int64(math.MinInt64), req["negbig"].Int64())
+			assert.Equal(t, uint64(math.MaxUint64), req["big"].Uint64())
+			assert.InDelta(t, 1.5, req["float"].Float64(), 0)
+			assert.True(t, req["bool"].Bool())
+			assert.Equal(t, -1500*time.Millisecond, req["dur"].Duration())
+			assert.True(t, ts.Equal(req["time"].Time()), "got %v", req["time"].Time())
+			assert.Equal(t, "boom", req["err"].String())
+			assert.Equal(t, []byte{1, 2, 3}, req["bytes"].Any())
+			assert.Nil(t, req["nil"].Any())
+			assert.Equal(t, []any{int64(1), int64(2)}, req["slice"].Any())
+			assert.Equal(t, []slog.Attr{slog.String("accept", "*/*")}, req["headers"].Group())
+
+			rec, err = dec.Decode()
+			require.NoError(t, err)
+			assert.Equal(t, "second", rec.Message)
+			assert.Equal(t, slog.LevelWarn, rec.Level)
+
+			_, err = dec.Decode()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 20, 41, 28, 515*1e6, time.UTC)
+
+	for format, newHandler := range formats {
+		t.Run(format, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := newHandler(buf, &slog.HandlerOptions{ReplaceAttr: flume.FixedTime(ts)})
+
+			rec := slog.NewRecord(ts, slog.LevelWarn+1, "hi", 0)
+			rec.Add("color", "red", slog.Group("props", "size", 5, "dur", time.Second))
+			require.NoError(t, h.Handle(context.Background(), rec))
+
+			out := bytes.NewBuffer(nil)
+			require.NoError(t, ToJSON(out, buf, format))
+
+			assert.JSONEq(t,
+				`{"time":"2024-01-01T20:41:28.515Z","level":"WARN+1","msg":"hi","color":"red","props":{"size":5,"dur":1000000000}}`,
+				out.String())
+		})
+	}
+}
+
+func TestReplay_source(t *testing.T) {
+	for format, newHandler := range formats {
+		t.Run(format, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			slog.New(newHandler(buf, &slog.HandlerOptions{AddSource: true})).Info("hi")
+
+			out := bytes.NewBuffer(nil)
+			require.NoError(t, Replay(context.Background(), slog.NewTextHandler(out, nil), buf, format))
+
+			assert.Contains(t, out.String(), "source.file=")
+			assert.Contains(t, out.String(), "binlog_test.go")
+		})
+	}
+}
+
+// nestedFrame returns a frame holding a map with an array nested depth levels deep.
+func nestedFrame(format string, depth int) []byte {
+	// map(1), "a", array(1)
+	head := []byte{0xa1, 0x61, 'a', 0x81}
+	if format == flume.MsgPackHandler {
+		head = []byte{0x81, 0xa1, 'a', 0x91}
+	}
+
+	payload := append(head[:3:3], bytes.Repeat(head[3:], depth-1)...)
+	payload = append(payload, 0x01)
+
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...) //nolint:gosec
+}
+
+func TestDecoder_depth(t *testing.T) {
+	for format := range formats {
+		t.Run(format, func(t *testing.T) {
+			dec, err := NewDecoder(bytes.NewReader(nestedFrame(format, maxDepth)), format)
+			require.NoError(t, err)
+
+			_, err = dec.DecodeValue()
+			require.NoError(t, err)
+		})
+	}
+
+	// tags nest too
+	payload := append([]byte{0xa1, 0x61, 'a'}, bytes.Repeat([]byte{0xc6}, maxDepth+1)...)
+	payload = append(payload, 0x01)
+
+	dec, err := NewDecoder(bytes.NewReader(append([]byte{0, 0, 0, byte(len(payload))}, payload...)), flume.CBORHandler)
+	require.NoError(t, err)
+
+	_, err = dec.DecodeValue()
+	require.ErrorIs(t, err, ErrInvalidFrame)
+	require.ErrorContains(t, err, "nested too deeply")
+}
+
+func TestDecoder_errors(t *testing.T) {
+	_, err := NewDecoder(nil, "json")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+
+	for format, newHandler := range formats {
+		t.Run(format, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			slog.New(newHandler(buf, nil)).Info("hi", "color", "red")
+			frame := buf.Bytes()
+
+			tests := map[string][]byte{
+				"truncated prefix":  frame[:2],
+				"truncated payload": frame[:len(frame)-1],
+				"trailing bytes":    append([]byte{0, 0, 0, byte(len(frame) - 3)}, append(frame[4:], 0)...),
+				"not a map":         {0, 0, 0, 1, 0x01},
+				"too large":         append([]byte{0xff, 0xff, 0xff, 0xff}, frame[4:]...),
+				"nested too deeply": nestedFrame(format, maxDepth+1),
+			}
+
+			for name, b := range tests {
+				t.Run(name, func(t *testing.T) {
+					dec, err := NewDecoder(bytes.NewReader(b), format)
+					require.NoError(t, err)
+
+					_, err = dec.Decode()
+					require.ErrorIs(t, err, ErrInvalidFrame)
+				})
+			}
+		})
+	}
+}
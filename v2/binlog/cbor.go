@@ -0,0 +1,184 @@
+package binlog
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+// cborDecoder decodes the subset of RFC 8949 which flume's cbor handler produces:
+// definite length items, tags for times and durations, and float64.
+type cborDecoder struct{}
+
+func (d cborDecoder) decodeValue(p *payload) (slog.Value, error) {
+	initial, err := p.byte()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	major, info := initial>>5, initial&0x1f
+
+	if major == 7 {
+		return d.decodeSimple(p, info)
+	}
+
+	n, err := d.argument(p, info)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return slog.Uint64Value(n), nil
+		}
+
+		return slog.Int64Value(int64(n)), nil
+	case 1:
+		if n > math.MaxInt64 {
+			return slog.Value{}, fmt.Errorf("%w: negative integer overflows int64", errUnsupported)
+		}
+
+		return slog.Int64Value(-1 - int64(n)), nil
+	case 2:
+		b, err := p.next(n)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.AnyValue(append([]byte(nil), b...)), nil
+	case 3:
+		b, err := p.next(n)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.StringValue(string(b)), nil
+	case 4:
+		return decodeArray(p, d, n)
+	case 5:
+		return decodeKeyedValues(p, d, n)
+	default: // 6: tag
+		return d.decodeTag(p, n)
+	}
+}
+
+func (cborDecoder) argument(p *payload, info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return p.uint(1 << (info - 24))
+	default:
+		return 0, fmt.Errorf("%w: cbor additional info %d", errUnsupported, info)
+	}
+}
+
+func (cborDecoder) decodeSimple(p *payload, info byte) (slog.Value, error) {
+	switch info {
+	case 20:
+		return slog.BoolValue(false), nil
+	case 21:
+		return slog.BoolValue(true), nil
+	case 22, 23:
+		// null and undefined
+		return slog.AnyValue(nil), nil
+	case 26:
+		bits, err := p.uint(4)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.Float64Value(float64(math.Float32frombits(uint32(bits)))), nil
+	case 27:
+		bits, err := p.uint(8)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.Float64Value(math.Float64frombits(bits)), nil
+	default:
+		return slog.Value{}, fmt.Errorf("%w: cbor simple value %d", errUnsupported, info)
+	}
+}
+
+func (d cborDecoder) decodeTag(p *payload, tag uint64) (slog.Value, error) {
+	err := p.enter()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	defer p.leave()
+
+	if tag == flume.CBORDurationTag {
+		return d.decodeDuration(p)
+	}
+
+	content, err := d.decodeValue(p)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	if tag != flume.CBORTimeTag {
+		// unknown tags are ignored
+		return content, nil
+	}
+
+	if content.Kind() != slog.KindString {
+		return slog.Value{}, fmt.Errorf("%w: time tag content is %v, not a string", errMalformed, content.Kind())
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, content.String())
+	if err != nil {
+		return slog.Value{}, fmt.Errorf("%w: time tag content: %w", errMalformed, err)
+	}
+
+	return slog.TimeValue(t), nil
+}
+
+// decodeDuration decodes the content of a duration tag, which is a map with integer keys,
+// so it can't be decoded with decodeKeyedValues.
+func (d cborDecoder) decodeDuration(p *payload) (slog.Value, error) {
+	initial, err := p.byte()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	if initial>>5 != 5 {
+		return slog.Value{}, fmt.Errorf("%w: duration tag content is not a map", errMalformed)
+	}
+
+	n, err := d.argument(p, initial&0x1f)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	var secs, nanos int64
+
+	for range n {
+		k, err := d.decodeValue(p)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		v, err := d.decodeValue(p)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		if k.Kind() != slog.KindInt64 || v.Kind() != slog.KindInt64 {
+			return slog.Value{}, fmt.Errorf("%w: duration tag content must have integer keys and values", errMalformed)
+		}
+
+		switch k.Int64() {
+		case 1:
+			secs = v.Int64()
+		case -9:
+			nanos = v.Int64()
+		}
+	}
+
+	return durationValue(secs, nanos), nil
+}
@@ -0,0 +1,173 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+// msgPackDecoder decodes the MessagePack format, including the timestamp
+// extension type and flume's duration extension type.
+type msgPackDecoder struct{}
+
+func (d msgPackDecoder) decodeValue(p *payload) (slog.Value, error) {
+	b, err := p.byte()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		// positive fixint
+		return slog.Int64Value(int64(b)), nil
+	case b >= 0xe0:
+		// negative fixint
+		return slog.Int64Value(int64(int8(b))), nil
+	case b&0xf0 == 0x80:
+		return decodeKeyedValues(p, d, uint64(b&0x0f))
+	case b&0xf0 == 0x90:
+		return decodeArray(p, d, uint64(b&0x0f))
+	case b&0xe0 == 0xa0:
+		return d.decodeString(p, uint64(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return slog.AnyValue(nil), nil
+	case 0xc2:
+		return slog.BoolValue(false), nil
+	case 0xc3:
+		return slog.BoolValue(true), nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := p.uint(1 << (b - 0xc4))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		bin, err := p.next(n)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.AnyValue(append([]byte(nil), bin...)), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := p.uint(1 << (b - 0xc7))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return d.decodeExt(p, n)
+	case 0xca:
+		bits, err := p.uint(4)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.Float64Value(float64(math.Float32frombits(uint32(bits)))), nil
+	case 0xcb:
+		bits, err := p.uint(8)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.Float64Value(math.Float64frombits(bits)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := p.uint(1 << (b - 0xcc))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		if u > math.MaxInt64 {
+			return slog.Uint64Value(u), nil
+		}
+
+		return slog.Int64Value(int64(u)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+
+		u, err := p.uint(size)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		// sign extend
+		shift := 64 - 8*size
+
+		return slog.Int64Value(int64(u<<shift) >> shift), nil //nolint:gosec
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(p, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := p.uint(1 << (b - 0xd9))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return d.decodeString(p, n)
+	case 0xdc, 0xdd:
+		n, err := p.uint(2 << (b - 0xdc))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return decodeArray(p, d, n)
+	case 0xde, 0xdf:
+		n, err := p.uint(2 << (b - 0xde))
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return decodeKeyedValues(p, d, n)
+	default:
+		return slog.Value{}, fmt.Errorf("%w: msgpack format 0x%x", errUnsupported, b)
+	}
+}
+
+func (msgPackDecoder) decodeString(p *payload, n uint64) (slog.Value, error) {
+	b, err := p.next(n)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	return slog.StringValue(string(b)), nil
+}
+
+func (msgPackDecoder) decodeExt(p *payload, n uint64) (slog.Value, error) {
+	typ, err := p.byte()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	data, err := p.next(n)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch int8(typ) {
+	case flume.MsgPackTimestampExt:
+		switch len(data) {
+		case 4:
+			return slog.TimeValue(time.Unix(int64(binary.BigEndian.Uint32(data)), 0)), nil
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			return slog.TimeValue(time.Unix(int64(v&0x3_ffff_ffff), int64(v>>34))), nil //nolint:gosec
+		case 12:
+			nsec := binary.BigEndian.Uint32(data)
+			sec := int64(binary.BigEndian.Uint64(data[4:])) //nolint:gosec
+
+			return slog.TimeValue(time.Unix(sec, int64(nsec))), nil
+		}
+	case flume.MsgPackDurationExt:
+		if len(data) == 8 {
+			return durationValue(0, int64(binary.BigEndian.Uint64(data))), nil //nolint:gosec
+		}
+	default:
+		// unknown extension types are passed through as raw bytes
+		return slog.AnyValue(append([]byte(nil), data...)), nil
+	}
+
+	return slog.Value{}, fmt.Errorf("%w: msgpack extension type %d with length %d", errUnsupported, int8(typ), len(data))
+}
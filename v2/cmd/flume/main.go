@@ -0,0 +1,161 @@
+// Command flume pretty-prints json and ltsv log streams, by re-rendering each record
+// through one of flume's handlers, colored term output by default:
+//
+//	kubectl logs -f pod | flume
+//	flume --log-format=json-pretty --log-levels=http=DBG,-sql app.log
+//
+// It reads the files named as arguments, or stdin if there are none, or for "-".
+// Each line which is a json object, with slog's or flume v1's field names, or an ltsv
+// record, with at least a message or a level, is rendered as a log record.  Other
+// lines are written unchanged.
+//
+// Records are filtered with the --log-level and --log-levels flags, using the
+// "logger" field, or flume v1's "name" field, as the logger name.  By default, all
+// records are shown.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "flume:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("flume", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flume [flags] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nPretty-prints json and ltsv log lines.  Reads stdin if no files are given.")
+		fmt.Fprintln(fs.Output(), "The default format is term-auto, which is colored when writing to a terminal.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+
+	opts := flume.HandlerOptions{
+		Level:     flume.LevelAll,
+		HandlerFn: flume.TermAutoHandlerFn(),
+	}
+
+	flume.RegisterFlags(fs, &opts)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	p := &printer{
+		h:       flume.NewHandler(stdout, &opts),
+		w:       stdout,
+		loggers: map[string]slog.Handler{},
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, name := range files {
+		err := p.printFile(name, stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type printer struct {
+	h *flume.Handler
+	w io.Writer
+	// handlers for each logger name, so records are enabled with the logger's level
+	loggers map[string]slog.Handler
+}
+
+func (p *printer) printFile(name string, stdin io.Reader) error {
+	if name == "-" {
+		return p.print(stdin)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.print(f)
+}
+
+func (p *printer) print(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if werr := p.printLine(line); werr != nil {
+				return werr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *printer) printLine(line []byte) error {
+	e, ok := parseLine(line)
+	if !ok {
+		_, err := p.w.Write(line)
+
+		return err
+	}
+
+	ctx := context.Background()
+
+	h := p.logger(e.logger)
+	if !h.Enabled(ctx, e.level) {
+		return nil
+	}
+
+	rec := slog.NewRecord(e.time, e.level, e.msg, 0)
+	rec.AddAttrs(e.attrs...)
+
+	return h.Handle(ctx, rec)
+}
+
+func (p *printer) logger(name string) slog.Handler {
+	if name == "" {
+		return p.h
+	}
+
+	h, ok := p.loggers[name]
+	if !ok {
+		h = p.h.Named(name)
+		p.loggers[name] = h
+	}
+
+	return h
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 10, 0, 0, 123000000, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want entry
+		ok   bool
+	}{
+		{
+			name: "slog json",
+			line: `{"time":"2024-01-01T10:00:00.123Z","level":"WARN+1","msg":"hi","logger":"http","n":1,"f":1.5,"ok":true,"req":{"path":"/"},"tags":["a",2]}` + "\n",
+			want: entry{
+				time:   ts,
+				level:  slog.LevelWarn + 1,
+				msg:    "hi",
+				logger: "http",
+				attrs: []slog.Attr{
+					slog.Int64("n", 1),
+					slog.Float64("f", 1.5),
+					slog.Bool("ok", true),
+					slog.Group("req", slog.String("path", "/")),
+					slog.Any("tags", []any{"a", int64(2)}),
+				},
+			},
+			ok: true,
+		},
+		{
+			name: "flume v1 json",
+			line: `{"time":"2024-01-01T10:00:00.123Z","level":"DBG","name":"sql","msg":"hi","caller":"db.go:12"}`,
+			want: entry{
+				time:   ts,
+				level:  slog.LevelDebug,
+				msg:    "hi",
+				logger: "sql",
+				attrs:  []slog.Attr{slog.String("caller", "db.go:12")},
+			},
+			ok: true,
+		},
+		{
+			name: "flume v1 ltsv",
+			line: "time:2024-01-01T10:00:00.123Z\tlevel:ERR\tname:sql\tmsg:hi\\tthere\terror:line1\\nline2",
+			want: entry{
+				time:   ts,
+				level:  slog.LevelError,
+				msg:    "hi\tthere",
+				logger: "sql",
+				attrs:  []slog.Attr{slog.String("error", "line1\nline2")},
+			},
+			ok: true,
+		},
+		{
+			name: "epoch time",
+			line: `{"ts":1704103200.5,"level":"info","msg":"hi"}`,
+			want: entry{time: time.Unix(1704103200, 5e8), level: slog.LevelInfo, msg: "hi"},
+			ok:   true,
+		},
+		{
+			name: "unparseable time and level are kept as attrs",
+			line: `{"time":"yesterday","level":"loud","msg":"hi"}`,
+			want: entry{
+				level: slog.LevelInfo,
+				msg:   "hi",
+				attrs: []slog.Attr{slog.String("time", "yesterday"), slog.String("level", "loud")},
+			},
+			ok: true,
+		},
+		{
+			name: "level only",
+			line: `{"level":"ERROR","error":"boom"}`,
+			want: entry{level: slog.LevelError, attrs: []slog.Attr{slog.String("error", "boom")}},
+			ok:   true,
+		},
+		{name: "plain text", line: "Starting server on :8080"},
+		{name: "json without msg or level", line: `{"status":200}`},
+		{name: "json array", line: `[1,2]`},
+		{name: "invalid json", line: `{"msg":`},
+		{name: "trailing content", line: `{"msg":"hi"} and more`},
+		{name: "ltsv without msg or level", line: "status:200\tpath:/"},
+		{name: "empty", line: "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, ok := parseLine([]byte(test.line))
+			assert.Equal(t, test.ok, ok)
+
+			if test.ok {
+				assert.Equal(t, test.want.time.UnixNano(), e.time.UnixNano())
+				assert.Equal(t, test.want.level, e.level)
+				assert.Equal(t, test.want.msg, e.msg)
+				assert.Equal(t, test.want.logger, e.logger)
+
+				if len(test.want.attrs) == 0 {
+					assert.Empty(t, e.attrs)
+				} else {
+					assert.Equal(t, test.want.attrs, e.attrs)
+				}
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := strings.Join([]string{
+		`{"time":"2024-01-01T10:00:00.123Z","level":"INFO","msg":"hello","logger":"http","status":200}`,
+		`not a log line`,
+		`{"time":"2024-01-01T10:00:01Z","level":"DEBUG","msg":"query","name":"sql"}`,
+		"time:2024-01-01T10:00:02Z\tlevel:WRN\tmsg:slow",
+		`{"msg":"no newline at the end"}`,
+	}, "\n")
+
+	out := bytes.NewBuffer(nil)
+
+	err := run([]string{"--log-format", "json", "--log-levels", "-sql"}, strings.NewReader(input), out, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.Join([]string{
+		`{"time":"2024-01-01T10:00:00.123Z","level":"INFO","msg":"hello","logger":"http","status":200}`,
+		`not a log line`,
+		`{"time":"2024-01-01T10:00:02Z","level":"WARN","msg":"slow"}`,
+		`{"level":"INFO","msg":"no newline at the end"}`,
+		``,
+	}, "\n"), out.String())
+}
+
+func TestRun_files(t *testing.T) {
+	dir := t.TempDir()
+
+	a := filepath.Join(dir, "a.log")
+	require.NoError(t, os.WriteFile(a, []byte(`{"level":"INFO","msg":"from a"}`+"\n"), 0o600))
+
+	b := filepath.Join(dir, "b.log")
+	require.NoError(t, os.WriteFile(b, []byte(`{"level":"INFO","msg":"from b"}`+"\n"), 0o600))
+
+	out := bytes.NewBuffer(nil)
+
+	err := run([]string{"--log-format", "text", a, "-", b}, strings.NewReader("stdin\n"), out, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "level=INFO msg=\"from a\"\nstdin\nlevel=INFO msg=\"from b\"\n", out.String())
+
+	err = run([]string{filepath.Join(dir, "missing.log")}, nil, out, nil)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRun_term(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+
+	err := run([]string{"--log-format", "term"},
+		strings.NewReader(`{"time":"2024-01-01T10:00:00.123Z","level":"WARN","msg":"hi","logger":"http","n":1}`), out, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 123000000, time.UTC).Local().Format("15:04:05.000")+
+		" http     |WRN| hi n=1\n", out.String())
+}
+
+func TestRun_flags(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+
+	err := run([]string{"--log-format", "xml"}, strings.NewReader(""), out, stderr)
+	require.ErrorContains(t, err, "unregistered handler: 'xml'")
+
+	stderr.Reset()
+
+	err = run([]string{"-h"}, strings.NewReader(""), out, stderr)
+	require.ErrorIs(t, err, flag.ErrHelp)
+	assert.Contains(t, stderr.String(), "usage: flume [flags] [file ...]")
+	assert.Contains(t, stderr.String(), "-log-levels")
+	assert.Empty(t, out.String())
+}
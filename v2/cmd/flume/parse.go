@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+// entry is a log record parsed from a line.
+type entry struct {
+	time   time.Time
+	level  slog.Level
+	msg    string
+	logger string
+	attrs  []slog.Attr
+}
+
+// The keys of the built-in fields, in slog's and flume v1's json and ltsv output, and
+// some common alternatives.
+var (
+	timeKeys   = []string{slog.TimeKey, "ts", "timestamp", "@timestamp"}
+	levelKeys  = []string{slog.LevelKey, "lvl", "severity"}
+	msgKeys    = []string{slog.MessageKey, "message"}
+	loggerKeys = []string{flume.LoggerKey, "name"}
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	// flume v1's ISO8601 encoder
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02 15:04:05.000Z0700",
+}
+
+// parseLine parses a json or ltsv log line.  It returns false if the line isn't a log
+// record, which is the case if it can't be parsed, or has neither a message nor a level.
+func parseLine(line []byte) (entry, bool) {
+	line = bytes.TrimSpace(line)
+
+	var attrs []slog.Attr
+
+	switch {
+	case len(line) > 0 && line[0] == '{':
+		var err error
+
+		attrs, err = parseJSON(line)
+		if err != nil {
+			return entry{}, false
+		}
+	case bytes.IndexByte(line, '\t') > 0 || bytes.IndexByte(line, ':') > 0:
+		var ok bool
+
+		attrs, ok = parseLTSV(string(line))
+		if !ok {
+			return entry{}, false
+		}
+	default:
+		return entry{}, false
+	}
+
+	var e entry
+
+	var hasMsg, hasLevel bool
+
+	rest := attrs[:0]
+
+	for _, a := range attrs {
+		switch {
+		case e.time.IsZero() && isKey(a.Key, timeKeys):
+			t, ok := parseTime(a.Value)
+			if !ok {
+				rest = append(rest, a)
+
+				continue
+			}
+
+			e.time = t
+		case !hasLevel && isKey(a.Key, levelKeys):
+			l, ok := parseLevel(a.Value)
+			if !ok {
+				rest = append(rest, a)
+
+				continue
+			}
+
+			e.level, hasLevel = l, true
+		case !hasMsg && isKey(a.Key, msgKeys) && a.Value.Kind() == slog.KindString:
+			e.msg, hasMsg = a.Value.String(), true
+		case e.logger == "" && isKey(a.Key, loggerKeys) && a.Value.Kind() == slog.KindString:
+			e.logger = a.Value.String()
+		default:
+			rest = append(rest, a)
+		}
+	}
+
+	if !hasMsg && !hasLevel {
+		return entry{}, false
+	}
+
+	if !hasLevel {
+		e.level = slog.LevelInfo
+	}
+
+	e.attrs = rest
+
+	return e, true
+}
+
+func isKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseTime(v slog.Value) (time.Time, bool) {
+	switch v.Kind() {
+	case slog.KindString:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v.String()); err == nil {
+				return t, true
+			}
+		}
+	case slog.KindInt64:
+		// epoch seconds
+		return time.Unix(v.Int64(), 0), true
+	case slog.KindFloat64:
+		// epoch seconds, like zap's default "ts"
+		sec, frac := math.Modf(v.Float64())
+
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+
+	return time.Time{}, false
+}
+
+func parseLevel(v slog.Value) (slog.Level, bool) {
+	var l slog.Leveler
+
+	var s string
+
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindInt64:
+		return slog.Level(v.Int64()), true
+	default:
+		return 0, false
+	}
+
+	err := flume.LevelValue(&l).Set(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return l.Level(), true
+}
+
+// parseJSON parses a json object into attrs, preserving the order of the properties.
+func parseJSON(line []byte) ([]slog.Attr, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok != json.Delim('{') {
+		return nil, errNotObject
+	}
+
+	attrs, err := decodeObject(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	// reject trailing content, like `{} not json`
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errNotObject
+	}
+
+	return attrs, nil
+}
+
+var errNotObject = errors.New("not a json object")
+
+// decodeObject decodes the properties of an object, after its opening brace.
+func decodeObject(dec *json.Decoder) ([]slog.Attr, error) {
+	var attrs []slog.Attr
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := tok.(string)
+
+		v, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, slog.Attr{Key: key, Value: v})
+	}
+
+	// closing brace
+	_, err := dec.Token()
+
+	return attrs, err
+}
+
+func decodeValue(dec *json.Decoder) (slog.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			attrs, err := decodeObject(dec)
+
+			return slog.GroupValue(attrs...), err
+		case '[':
+			var items []any
+
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return slog.Value{}, err
+				}
+
+				items = append(items, v.Any())
+			}
+
+			// closing bracket
+			_, err := dec.Token()
+
+			return slog.AnyValue(items), err
+		}
+	case string:
+		return slog.StringValue(tok), nil
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return slog.Int64Value(i), nil
+		}
+
+		f, err := tok.Float64()
+
+		return slog.Float64Value(f), err
+	case bool:
+		return slog.BoolValue(tok), nil
+	}
+
+	return slog.AnyValue(nil), nil
+}
+
+// parseLTSV parses a line of tab separated "key:value" fields, as written by flume v1's
+// ltsv encoder.
+func parseLTSV(line string) ([]slog.Attr, bool) {
+	fields := strings.Split(line, "\t")
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \"{") {
+			return nil, false
+		}
+
+		attrs = append(attrs, slog.String(key, unescapeLTSV(value)))
+	}
+
+	return attrs, true
+}
+
+var ltsvUnescaper = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r")
+
+func unescapeLTSV(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	return ltsvUnescaper.Replace(s)
+}
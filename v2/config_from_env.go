@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -17,7 +18,22 @@ const (
 	JSONHandler      = "json"
 	TermHandler      = "term"
 	TermColorHandler = "term-color"
-	NoopHandler      = "noop"
+	// TermAutoHandler selects between TermColorHandler and TermHandler, depending on
+	// whether the output is a terminal, and the NO_COLOR and FORCE_COLOR environment variables.
+	TermAutoHandler = "term-auto"
+	NoopHandler     = "noop"
+	// PrettyJSONHandler renders each record as indented, multi-line json.  See NewPrettyJSONHandler.
+	PrettyJSONHandler = "json-pretty"
+	// CBORHandler encodes records as length-prefixed CBOR frames.  See NewCBORHandler.
+	CBORHandler = "cbor"
+	// MsgPackHandler encodes records as length-prefixed MessagePack frames.  See NewMsgPackHandler.
+	MsgPackHandler = "msgpack"
+	// SyslogHandler formats records as RFC 5424 syslog messages.  See NewSyslogHandler.
+	SyslogHandler = "syslog"
+	// JournaldHandler sends records to systemd-journald's native protocol socket.  See NewJournaldHandler.
+	JournaldHandler = "journald"
+	// GELFHandler writes GELF messages, for Graylog.  See NewGELFHandler.
+	GELFHandler = "gelf"
 )
 
 var defaultConfigEnvVars = []string{"FLUME"}
@@ -46,6 +62,11 @@ func DefaultConfigEnvVars() []string {
 // If an environment variable with a value is found, but parsing
 // fails, an error is returned.
 //
+// The output can be set with a <name>_OUTPUT variable, like FLUME_OUTPUT, which can
+// be "stdout", "stderr", or the path of a file to append to.  See UnmarshalEnv for the
+// other per-field variables.  flume owns the file: it's kept open while it's the output,
+// reused if a later call names the same path, and closed when a later call replaces it.
+//
 // If envvars is empty, it defaults to DefaultConfigEnvVars().
 func ConfigFromEnv(envvars ...string) error {
 	if len(envvars) == 0 {
@@ -59,11 +80,38 @@ func ConfigFromEnv(envvars ...string) error {
 		return err
 	}
 
-	Default().SetHandlerOptions(&c)
+	envOutput.Lock()
+	defer envOutput.Unlock()
+
+	w, f, err := outputFromEnv(envvars)
+	if err != nil {
+		return err
+	}
+
+	if w == nil {
+		Default().SetHandlerOptions(&c)
+
+		return nil
+	}
+
+	Default().setOutAndHandlerOptions(w, &c)
+
+	if envOutput.f != nil && envOutput.f != f {
+		_ = envOutput.f.Close()
+	}
+
+	envOutput.f = f
 
 	return nil
 }
 
+// envOutput is the file opened for the <name>_OUTPUT variable by the last call to
+// ConfigFromEnv, so it can be closed when it's replaced.
+var envOutput struct {
+	sync.Mutex
+	f *os.File
+}
+
 // MustConfigFromEnv is like ConfigFromEnv, but panics on error.
 // Like ConfigFromEnv, it always enables logging even when no environment variable is set.
 func MustConfigFromEnv(envvars ...string) {
@@ -76,7 +124,10 @@ func MustConfigFromEnv(envvars ...string) {
 // UnmarshalEnv reads handler options from an environment variable.  The first environment
 // variable in the list with a non-empty value will be unmarshaled into the options arg.
 //
-// The first argument must not be nil.
+// The first argument must not be nil.  Like UnmarshalJSON, the options in the environment
+// are merged over the existing options.  That includes a levels string: it replaces the
+// per-logger levels, and the default level if it sets "*", and keeps the other options.
+// Earlier versions replaced all the options with the levels string's.
 //
 // The value of the environment variable can be either json, or a levels string:
 //
@@ -95,19 +146,36 @@ func MustConfigFromEnv(envvars ...string) {
 //	                          // format as the "level" property)
 //	  "addSource": <bool>,
 //	  "addCaller": <bool>,    // v1 alias for "addSource"; if both set, "addSource" wins
+//	  "addStacktrace": <str>, // adds stack traces to records at or above this level
+//	  "stacktraceSkip": <int>,// frames to skip at the top of stack traces
+//	  "trimSource": {         // shortens source locations, see TrimSource
+//	    "moduleRelative": <bool>,
+//	    "pathSegments": <int>,
+//	    "shortFunction": <bool>
+//	  },
+//	  "attrs": <obj>,         // attrs added to every record, see HandlerOptions.UnmarshalJSON
+//	  "fields": <obj>,        // alias for "attrs"; if both set, "attrs" wins
+//	  "errorFallback": <str>, // "stderr" or "stdout": reports sink errors, see ErrorPolicy
+//	  "limits": {             // limits the size of records, see LimitsMiddleware
+//	    "maxMessageLength": <int>,
+//	    "maxStringLength": <int>,
+//	    "maxAttrs": <int>,
+//	    "maxGroupDepth": <int>,
+//	    "marker": <str>
+//	  },
 //	}
 //
 // Level strings are in the form:
 //
 //		 Levels    = Directive {"," Directive} .
 //	  Directive = logger | "-" logger | logger "=" Level | "*" .
-//	  Level     = LevelName [ "-" offset ] | int .
-//	  LevelName = "DEBUG" | "DBG" | "INFO" | "INF" |
-//	              "WARN" | "WRN" | "ERROR" | "ERR" |
-//	              "ALL" | "OFF" | ""
+//	  Level     = LevelName [ ( "+" | "-" ) offset ] | int .
+//	  LevelName = "TRACE" | "TRC" | "DEBUG" | "DBG" | "INFO" | "INF" |
+//	              "NOTICE" | "NTC" | "WARN" | "WRN" | "ERROR" | "ERR" |
+//	              "FATAL" | "FTL" | "ALL" | "OFF" | ""
 //
 // Where `logger` is the name of a logger.  "*" sets the default level.  LevelName is
-// case-insensitive.
+// case-insensitive, and may also be any name or abbreviation added with RegisterLevel.
 //
 // Example:
 //
@@ -120,7 +188,28 @@ func MustConfigFromEnv(envvars ...string) {
 // - sets the authz logger to ERR
 // - sets the authn logger to level 1 (slog.LevelInfo + 1)
 // - sets the keys logger to WARN (slog.LevelWarn == 4)
+//
+// Options can also be set with per-field variables, named after each of envvars with a
+// suffix, for deployment tools which can't easily set json values:
+//
+//	FLUME_LEVEL=WRN                  // like "level"
+//	FLUME_LEVELS=http=DBG,-sql       // like "levels", as a levels string
+//	FLUME_HANDLER=json               // like "handler"
+//	FLUME_ADD_SOURCE=true            // like "addSource", parsed with strconv.ParseBool
+//
+// Per-field variables are merged over the options from the json or levels string
+// variable.  Each field is taken from the first of envvars with the suffix set.
+// FLUME_OUTPUT is read by ConfigFromEnv, since the output isn't one of the options.
 func UnmarshalEnv(o *HandlerOptions, envvars ...string) error {
+	err := unmarshalEnvConfig(o, envvars)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalEnvFields(o, envvars)
+}
+
+func unmarshalEnvConfig(o *HandlerOptions, envvars []string) error {
 	for _, v := range envvars {
 		configString := os.Getenv(v)
 		if configString == "" {
@@ -137,27 +226,144 @@ func UnmarshalEnv(o *HandlerOptions, envvars ...string) error {
 		}
 
 		// parse the value like a levels string
-		var levels Levels
-
-		err := levels.UnmarshalText([]byte(configString))
+		err := setLevelsString(o, configString)
 		if err != nil {
 			return fmt.Errorf("parsing levels string from environment variable %v: %w", v, err)
 		}
 
-		opts := HandlerOptions{}
-		if defLvl, ok := levels["*"]; ok {
-			opts.Level = defLvl
+		return nil
+	}
+
+	return nil
+}
+
+// setLevelsString sets the levels in a levels string, and the default level, if the
+// string sets it with "*".
+func setLevelsString(o *HandlerOptions, s string) error {
+	var levels Levels
+
+	err := levels.UnmarshalText([]byte(s))
+	if err != nil {
+		return err
+	}
+
+	lo := levelsStringOptions(levels)
+	if lo.Level != nil {
+		o.Level = lo.Level
+	}
+
+	o.Levels = lo.Levels
+
+	return nil
+}
+
+// levelsStringOptions returns the options for a levels string, where "*" sets the default level.
+func levelsStringOptions(levels Levels) HandlerOptions {
+	opts := HandlerOptions{}
+	if defLvl, ok := levels["*"]; ok {
+		opts.Level = defLvl
+
+		delete(levels, "*")
+	}
+
+	opts.Levels = levels
+
+	return opts
+}
+
+// envFields are the per-field environment variables, by suffix, and how each is
+// decoded into the options.
+var envFields = []struct {
+	suffix string
+	set    func(o *HandlerOptions, value string) error
+}{
+	{"_LEVEL", func(o *HandlerOptions, value string) error {
+		l, err := parseLevel(value)
+		if err != nil {
+			return err
+		}
+
+		o.Level = l
+
+		return nil
+	}},
+	{"_LEVELS", setLevelsString},
+	{"_HANDLER", func(o *HandlerOptions, value string) error {
+		fn := LookupHandlerFn(value)
+		if fn == nil {
+			return fmt.Errorf("%w: '%v'", ErrUnregisteredHandler, value)
+		}
+
+		o.HandlerFn = fn
 
-			delete(levels, "*")
+		return nil
+	}},
+	{"_ADD_SOURCE", func(o *HandlerOptions, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err //nolint:wrapcheck
 		}
 
-		opts.Levels = levels
-		*o = opts
+		o.AddSource = b
+
+		return nil
+	}},
+}
+
+func unmarshalEnvFields(o *HandlerOptions, envvars []string) error {
+	for _, field := range envFields {
+		for _, v := range envvars {
+			name := v + field.suffix
+
+			value := os.Getenv(name)
+			if value == "" {
+				continue
+			}
+
+			err := field.set(o, value)
+			if err != nil {
+				return fmt.Errorf("parsing environment variable %v: %w", name, err)
+			}
+
+			break
+		}
 	}
 
 	return nil
 }
 
+// outputFromEnv returns the output named by the first <name>_OUTPUT variable which is
+// set, or nil if none are, and the file, if the output is a file.  The caller must hold
+// envOutput's lock.
+func outputFromEnv(envvars []string) (io.Writer, *os.File, error) {
+	for _, v := range envvars {
+		name := v + "_OUTPUT"
+
+		value := os.Getenv(name)
+		switch strings.ToLower(value) {
+		case "":
+			continue
+		case "stdout":
+			return os.Stdout, nil, nil
+		case "stderr":
+			return os.Stderr, nil, nil
+		}
+
+		if envOutput.f != nil && envOutput.f.Name() == value {
+			return envOutput.f, envOutput.f, nil
+		}
+
+		f, err := os.OpenFile(value, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening output from environment variable %v: %w", name, err)
+		}
+
+		return f, f, nil
+	}
+
+	return nil, nil, nil
+}
+
 var handlerFns sync.Map
 
 var initHandlerFnsOnce sync.Once
@@ -180,6 +386,29 @@ func resetBuiltInHandlerFns() {
 	registerHandlerFn(TermColorHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
 		return console.NewHandler(w, termHandlerOptions(opts))
 	})
+	registerHandlerFn(TermAutoHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+		termOpts := termHandlerOptions(opts)
+		termOpts.NoColor = !colorEnabled(w)
+
+		return console.NewHandler(w, termOpts)
+	})
+	registerHandlerFn(PrettyJSONHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+		prettyOpts := &PrettyJSONOptions{}
+		if opts != nil {
+			prettyOpts.HandlerOptions = *opts
+		}
+
+		return NewPrettyJSONHandler(w, prettyOpts)
+	})
+	registerHandlerFn(CBORHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+		return NewCBORHandler(w, opts)
+	})
+	registerHandlerFn(MsgPackHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+		return NewMsgPackHandler(w, opts)
+	})
+	registerHandlerFn(SyslogHandler, NewSyslogHandlerFn("", "", nil))
+	registerHandlerFn(JournaldHandler, NewJournaldHandlerFn(DefaultJournaldSocket, nil))
+	registerHandlerFn(GELFHandler, NewGELFHandlerFn("", "", nil))
 	registerHandlerFn(NoopHandler, func(_ string, _ io.Writer, _ *slog.HandlerOptions) slog.Handler {
 		return noop
 	})
@@ -241,6 +470,26 @@ func TermColorHandlerFn() HandlerFn {
 	return LookupHandlerFn(TermColorHandler)
 }
 
+// TermAutoHandlerFn is shorthand for LookupHandlerFn("term-auto").  Will never be nil.
+func TermAutoHandlerFn() HandlerFn {
+	return LookupHandlerFn(TermAutoHandler)
+}
+
+// PrettyJSONHandlerFn is shorthand for LookupHandlerFn("json-pretty").  Will never be nil.
+func PrettyJSONHandlerFn() HandlerFn {
+	return LookupHandlerFn(PrettyJSONHandler)
+}
+
+// CBORHandlerFn is shorthand for LookupHandlerFn("cbor").  Will never be nil.
+func CBORHandlerFn() HandlerFn {
+	return LookupHandlerFn(CBORHandler)
+}
+
+// MsgPackHandlerFn is shorthand for LookupHandlerFn("msgpack").  Will never be nil.
+func MsgPackHandlerFn() HandlerFn {
+	return LookupHandlerFn(MsgPackHandler)
+}
+
 // NoopHandlerFn is shorthand for LookupHandlerFn("noop").  Will never be nil.
 func NoopHandlerFn() HandlerFn {
 	return LookupHandlerFn(NoopHandler)
@@ -258,6 +507,32 @@ func registerHandlerFn(name string, fn HandlerFn) {
 	handlerFns.Store(name, fn)
 }
 
+// abbreviateCustomLevels wraps a ReplaceAttr function, and renders levels named
+// after custom levels with their registered abbreviations.  The console handler
+// only knows slog's levels, and colors the abbreviation according to the slog
+// level range the custom level falls in.
+func abbreviateCustomLevels(replaceAttr func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		switch {
+		case replaceAttr != nil:
+			a = replaceAttr(groups, a)
+		case len(groups) == 0 && a.Key == slog.MessageKey && a.Value.Kind() == slog.KindString:
+			// the console handler only trims messages if there is no ReplaceAttr function
+			a.Value = slog.StringValue(strings.TrimSpace(a.Value.String()))
+		}
+
+		if len(groups) > 0 || a.Key != slog.LevelKey || a.Value.Kind() != slog.KindAny {
+			return a
+		}
+
+		if l, ok := a.Value.Any().(slog.Level); ok && hasCustomLevelName(l) {
+			a.Value = slog.StringValue(formatLevel(l, true))
+		}
+
+		return a
+	}
+}
+
 func termHandlerOptions(opts *slog.HandlerOptions) *console.HandlerOptions {
 	// todo: it would be nice if consumers could tweak this, either programatically
 	// or via configuration, but that would mean exposing the dependency on console-slog,
@@ -275,7 +550,7 @@ func termHandlerOptions(opts *slog.HandlerOptions) *console.HandlerOptions {
 
 	return &console.HandlerOptions{
 		AddSource:          opts.AddSource,
-		ReplaceAttr:        opts.ReplaceAttr,
+		ReplaceAttr:        abbreviateCustomLevels(opts.ReplaceAttr),
 		Level:              opts.Level,
 		Theme:              theme,
 		TimeFormat:         "15:04:05.000",
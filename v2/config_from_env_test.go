@@ -5,7 +5,9 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -81,6 +83,85 @@ func TestUnmarshalEnv(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "per-field vars",
+			env: map[string]string{
+				"FLUME_LEVEL":      "WRN",
+				"FLUME_LEVELS":     "http=DBG,-sql",
+				"FLUME_HANDLER":    "json",
+				"FLUME_ADD_SOURCE": "1",
+			},
+			envvars: DefaultConfigEnvVars(),
+			expected: HandlerOptions{
+				Level:     LevelWarn,
+				Levels:    Levels{"http": LevelDebug, "sql": LevelOff},
+				HandlerFn: JSONHandlerFn(),
+				AddSource: true,
+			},
+		},
+		{
+			name: "per-field vars merged over json",
+			env: map[string]string{
+				"FLUME":       `{"level":"ERR","addSource":true,"levels":"http=DBG"}`,
+				"FLUME_LEVEL": "WRN",
+			},
+			envvars: DefaultConfigEnvVars(),
+			expected: HandlerOptions{
+				Level:     LevelWarn,
+				Levels:    Levels{"http": LevelDebug},
+				AddSource: true,
+			},
+		},
+		{
+			name: "per-field vars merged over levels string",
+			env: map[string]string{
+				"FLUME":            `*=ERR,http=DBG`,
+				"FLUME_ADD_SOURCE": "true",
+			},
+			envvars: DefaultConfigEnvVars(),
+			expected: HandlerOptions{
+				Level:     LevelError,
+				Levels:    Levels{"http": LevelDebug},
+				AddSource: true,
+			},
+		},
+		{
+			name: "per-field vars use the first prefix set",
+			env: map[string]string{
+				"APP_LEVEL":        "ERR",
+				"LOGCONFIG_LEVEL":  "WRN",
+				"LOGCONFIG_LEVELS": "http",
+			},
+			envvars: []string{"APP", "LOGCONFIG"},
+			expected: HandlerOptions{
+				Level:  LevelError,
+				Levels: Levels{"http": LevelAll},
+			},
+		},
+		{
+			name: "per-field level error",
+			env: map[string]string{
+				"FLUME_LEVEL": "loud",
+			},
+			envvars:     DefaultConfigEnvVars(),
+			expectError: "parsing environment variable FLUME_LEVEL: invalid log level 'loud'",
+		},
+		{
+			name: "per-field bool error",
+			env: map[string]string{
+				"FLUME_ADD_SOURCE": "yes please",
+			},
+			envvars:     DefaultConfigEnvVars(),
+			expectError: `parsing environment variable FLUME_ADD_SOURCE: strconv.ParseBool: parsing "yes please": invalid syntax`,
+		},
+		{
+			name: "per-field handler error",
+			env: map[string]string{
+				"FLUME_HANDLER": "xml",
+			},
+			envvars:     DefaultConfigEnvVars(),
+			expectError: "parsing environment variable FLUME_HANDLER: unregistered handler: 'xml'",
+		},
 	}
 
 	for _, test := range tests {
@@ -103,6 +184,44 @@ func TestUnmarshalEnv(t *testing.T) {
 	}
 }
 
+func TestUnmarshalEnv_merge(t *testing.T) {
+	opts := HandlerOptions{
+		Level:     slog.LevelWarn,
+		Levels:    Levels{"sql": LevelOff},
+		HandlerFn: JSONHandlerFn(),
+		AddSource: true,
+	}
+
+	// a levels string without "*" replaces the levels, and keeps the rest
+	t.Setenv("FLUME", "http=DBG")
+	t.Setenv("LOGCONFIG", "*=ERR")
+	require.NoError(t, UnmarshalEnv(&opts, "FLUME", "LOGCONFIG"))
+	assertHandlerOptionsEqual(t, HandlerOptions{
+		Level:     slog.LevelWarn,
+		Levels:    Levels{"http": slog.LevelDebug},
+		HandlerFn: JSONHandlerFn(),
+		AddSource: true,
+	}, opts, "")
+
+	t.Setenv("FLUME", "*=INF")
+	require.NoError(t, UnmarshalEnv(&opts, "FLUME"))
+	assertHandlerOptionsEqual(t, HandlerOptions{
+		Level:     slog.LevelInfo,
+		Levels:    Levels{},
+		HandlerFn: JSONHandlerFn(),
+		AddSource: true,
+	}, opts, "")
+
+	t.Setenv("FLUME", `{"levels":"boot=DBG"}`)
+	require.NoError(t, UnmarshalEnv(&opts, "FLUME"))
+	assertHandlerOptionsEqual(t, HandlerOptions{
+		Level:     slog.LevelInfo,
+		Levels:    Levels{"boot": slog.LevelDebug},
+		HandlerFn: JSONHandlerFn(),
+		AddSource: true,
+	}, opts, "")
+}
+
 func TestConfigFromEnv(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -175,6 +294,64 @@ func TestConfigFromEnv(t *testing.T) {
 	}
 }
 
+func TestConfigFromEnv_output(t *testing.T) {
+	ogOpts := Default().HandlerOptions()
+	ogOut := Default().Out()
+
+	t.Cleanup(func() {
+		Default().SetOut(ogOut)
+		Default().SetHandlerOptions(ogOpts)
+	})
+
+	t.Setenv("FLUME_OUTPUT", "STDERR")
+	require.NoError(t, ConfigFromEnv())
+	assert.Equal(t, os.Stderr, Default().Out())
+
+	path := filepath.Join(t.TempDir(), "flume.log")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	t.Setenv("FLUME_OUTPUT", path)
+	t.Setenv("FLUME_HANDLER", "json")
+	require.NoError(t, ConfigFromEnv())
+
+	f, ok := Default().Out().(*os.File)
+	require.True(t, ok)
+
+	slog.New(Default()).Info("hi")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "first\n{", "should append to the file")
+	assert.Contains(t, string(content), `"msg":"hi"`)
+
+	// the same path reuses the open file
+	require.NoError(t, ConfigFromEnv())
+	assert.Same(t, f, Default().Out())
+
+	t.Setenv("FLUME_OUTPUT", filepath.Join(path, "missing", "flume.log"))
+	require.ErrorContains(t, ConfigFromEnv(), "opening output from environment variable FLUME_OUTPUT")
+	assert.Same(t, f, Default().Out(), "should keep the output on error")
+
+	// replacing the file closes it
+	path2 := filepath.Join(t.TempDir(), "flume2.log")
+	t.Setenv("FLUME_OUTPUT", path2)
+	require.NoError(t, ConfigFromEnv())
+
+	f2, ok := Default().Out().(*os.File)
+	require.True(t, ok)
+	assert.NotSame(t, f, f2)
+
+	_, err = f.WriteString("closed")
+	require.ErrorIs(t, err, os.ErrClosed)
+
+	t.Setenv("FLUME_OUTPUT", "stdout")
+	require.NoError(t, ConfigFromEnv())
+	assert.Equal(t, os.Stdout, Default().Out())
+
+	_, err = f2.WriteString("closed")
+	require.ErrorIs(t, err, os.ErrClosed)
+}
+
 func TestRegisterHandlerFn(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -241,16 +418,22 @@ func TestRegisterHandlerFn(t *testing.T) {
 }
 
 func TestBuiltInHandlers(t *testing.T) {
+	// term-auto handler is sensitive to these
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+
 	theme := console.NewDefaultTheme()
 
 	builtIns := map[string]string{
-		TermHandler: "blue     |INF| hi\n",
+		TermHandler:     "blue     |INF| hi\n",
+		TermAutoHandler: "blue     |INF| hi\n",
 		TermColorHandler: styled("blue    ", theme.Header) + " " +
 			styled("|", theme.Header) + styled("INF", theme.LevelInfo) + styled("|", theme.Header) + " " +
 			styled("hi", theme.Message) + "\n",
-		TextHandler: "level=INFO msg=hi logger=blue\n",
-		JSONHandler: `{"level":"INFO","msg":"hi","logger":"blue"}` + "\n",
-		NoopHandler: "",
+		TextHandler:       "level=INFO msg=hi logger=blue\n",
+		JSONHandler:       `{"level":"INFO","msg":"hi","logger":"blue"}` + "\n",
+		NoopHandler:       "",
+		PrettyJSONHandler: "{\n  \"level\": \"INFO\",\n  \"logger\": \"blue\",\n  \"msg\": \"hi\"\n}\n",
 	}
 	for name, want := range builtIns {
 		handlerFn := LookupHandlerFn(name)
@@ -0,0 +1,255 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPollInterval is the default interval at which WatchConfigFile checks
+// the file for changes.
+const DefaultConfigPollInterval = 5 * time.Second
+
+// ConfigFromFile enables logging and configures flume from a configuration file.  It
+// is like ConfigFromEnv, but reads the options from a file.  See UnmarshalFile for the
+// formats.
+//
+// Calling this function always enables logging by switching the default handler
+// from noop to a text handler writing to stdout at LevelInfo, with the options in
+// the file applied.
+//
+// If the file can't be read or parsed, an error is returned, and the default handler
+// is not changed.
+func ConfigFromFile(path string) error {
+	var c HandlerOptions
+
+	err := UnmarshalFile(&c, path)
+	if err != nil {
+		return err
+	}
+
+	Default().SetHandlerOptions(&c)
+
+	return nil
+}
+
+// MustConfigFromFile is like ConfigFromFile, but panics on error.
+func MustConfigFromFile(path string) {
+	err := ConfigFromFile(path)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// UnmarshalFile reads handler options from a file.  The first argument must not be nil.
+//
+// The file can be json, using the same schema as UnmarshalEnv, or the equivalent yaml:
+//
+//	level: INF
+//	handler: json
+//	levels:
+//	  http: DBG
+//	  sql: OFF
+//
+// Files with a ".json" extension are parsed as json, and files with a ".yaml" or ".yml"
+// extension are parsed as yaml.  Otherwise, files starting with "{" are parsed as json,
+// and anything else as yaml.  An empty file resets the options to the defaults.
+func UnmarshalFile(o *HandlerOptions, path string) error {
+	var c HandlerOptions
+
+	err := mergeConfigFile(&c, path)
+	if err != nil {
+		return err
+	}
+
+	*o = c
+
+	return nil
+}
+
+// mergeConfigFile reads a file, and merges its options over o.
+func mergeConfigFile(o *HandlerOptions, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading configuration file %v: %w", path, err)
+	}
+
+	return unmarshalConfigFile(o, path, data)
+}
+
+// unmarshalConfigFile merges the options in a file over o.  If the file can't be parsed,
+// o is not modified.
+func unmarshalConfigFile(o *HandlerOptions, path string, data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		return unmarshalYAMLConfig(o, path, data)
+	default:
+		if trimmed[0] != '{' {
+			return unmarshalYAMLConfig(o, path, data)
+		}
+	}
+
+	c := o.Clone()
+
+	err := json.Unmarshal(data, c)
+	if err != nil {
+		return fmt.Errorf("parsing configuration file %v: %w", path, err)
+	}
+
+	*o = *c
+
+	return nil
+}
+
+// unmarshalYAMLConfig converts yaml to json, so the options are parsed by
+// HandlerOptions.UnmarshalJSON, and the two formats can't drift apart.
+func unmarshalYAMLConfig(o *HandlerOptions, path string, data []byte) error {
+	var v any
+
+	err := yaml.Unmarshal(data, &v)
+	if err != nil {
+		return fmt.Errorf("parsing configuration file %v: %w", path, err)
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("parsing configuration file %v: %w", path, err)
+	}
+
+	c := o.Clone()
+
+	err = json.Unmarshal(js, c)
+	if err != nil {
+		return fmt.Errorf("parsing configuration file %v: %w", path, err)
+	}
+
+	*o = *c
+
+	return nil
+}
+
+// WatchConfigFile is like ConfigFromFile, but also watches the file for changes, and
+// applies new options to the default handler until ctx is done.
+//
+// The file is polled every interval, which defaults to DefaultConfigPollInterval.
+// Polling the content, rather than watching for file system events, works with
+// Kubernetes ConfigMap volumes, which are updated by atomically swapping a symlink
+// to a new directory.  Other writers should also replace the file atomically, i.e.
+// by renaming a temporary file, or a poll may read a partially written file.
+//
+// The options in the file are merged over the handler's options when WatchConfigFile is
+// called, so options set in code, like Middleware and ReplaceAttrs, are kept, unless
+// the file overrides them.  Each change to the file is applied over those same options,
+// so removing a property from the file restores the option set in code.
+//
+// When new options are applied, an info record is logged to a logger named "flume".
+// If the changed file can't be read or parsed, an error record is logged, and the
+// last good options are kept.
+//
+// The file is read once before WatchConfigFile returns.  If that fails, the error is
+// returned, and the file is not watched.
+func WatchConfigFile(ctx context.Context, path string, interval time.Duration) error {
+	return watchConfigFile(ctx, Default(), path, interval)
+}
+
+func watchConfigFile(ctx context.Context, h *Handler, path string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading configuration file %v: %w", path, err)
+	}
+
+	base := h.HandlerOptions()
+	c := base.Clone()
+
+	err = unmarshalConfigFile(c, path, data)
+	if err != nil {
+		return err
+	}
+
+	h.SetHandlerOptions(c)
+
+	w := &configFileWatcher{h: h, base: base, path: path, data: data}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.poll(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+type configFileWatcher struct {
+	h *Handler
+	// the handler's options when the watch started, which the file is merged over
+	base *HandlerOptions
+	path string
+	// the content of the file when it was last polled
+	data []byte
+	// the last read error, which is only logged when it changes
+	readErr string
+}
+
+func (w *configFileWatcher) poll(ctx context.Context) {
+	logger := slog.New(w.h.Named("flume"))
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		if err.Error() != w.readErr {
+			w.readErr = err.Error()
+			logger.ErrorContext(ctx, "failed to read configuration file, keeping the last configuration",
+				"path", w.path, "error", err)
+		}
+
+		return
+	}
+
+	w.readErr = ""
+
+	if bytes.Equal(data, w.data) {
+		return
+	}
+
+	w.data = data
+
+	c := w.base.Clone()
+
+	err = unmarshalConfigFile(c, w.path, data)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to parse configuration file, keeping the last configuration",
+			"path", w.path, "error", err)
+
+		return
+	}
+
+	w.h.SetHandlerOptions(c)
+
+	logger.InfoContext(ctx, "configuration changed", "path", w.path)
+}
@@ -0,0 +1,262 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		expected    HandlerOptions
+		expectError string
+	}{
+		{
+			name:     "json",
+			file:     "flume.json",
+			content:  `{"level":"WRN","levels":{"http":"DBG"}}`,
+			expected: HandlerOptions{Level: slog.LevelWarn, Levels: Levels{"http": slog.LevelDebug}},
+		},
+		{
+			name: "yaml",
+			file: "flume.yaml",
+			content: `
+level: WRN
+addSource: true
+levels:
+  http: DBG
+  sql: off
+`,
+			expected: HandlerOptions{
+				Level:     slog.LevelWarn,
+				AddSource: true,
+				Levels:    Levels{"http": slog.LevelDebug, "sql": LevelOff},
+			},
+		},
+		{
+			name:     "yml",
+			file:     "flume.yml",
+			content:  `level: -4`,
+			expected: HandlerOptions{Level: slog.LevelDebug},
+		},
+		{
+			name:     "sniff json",
+			file:     "flume.conf",
+			content:  ` {"level":"WRN"}`,
+			expected: HandlerOptions{Level: slog.LevelWarn},
+		},
+		{
+			name:     "sniff yaml",
+			file:     "flume",
+			content:  `level: WRN`,
+			expected: HandlerOptions{Level: slog.LevelWarn},
+		},
+		{
+			name:    "empty",
+			file:    "flume.yaml",
+			content: "\n",
+		},
+		{
+			name:        "json parse error",
+			file:        "flume.json",
+			content:     `level: WRN`,
+			expectError: "parsing configuration file %v: invalid character 'l' looking for beginning of value",
+		},
+		{
+			name:        "yaml parse error",
+			file:        "flume.yaml",
+			content:     "level: [",
+			expectError: "parsing configuration file %v: yaml: line 1: did not find expected node content",
+		},
+		{
+			name:        "invalid options",
+			file:        "flume.yaml",
+			content:     "level: loud",
+			expectError: "parsing configuration file %v: invalid log level 'loud': slog: level string \"LOUD\": unknown name",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			require.NoError(t, os.WriteFile(path, []byte(test.content), 0o600))
+
+			opts := HandlerOptions{Level: slog.LevelError}
+
+			err := UnmarshalFile(&opts, path)
+			if test.expectError != "" {
+				require.Error(t, err)
+				assert.Equal(t, strings.ReplaceAll(test.expectError, "%v", path), err.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assertHandlerOptionsEqual(t, test.expected, opts, "")
+		})
+	}
+
+	err := UnmarshalFile(&HandlerOptions{}, filepath.Join(t.TempDir(), "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConfigFromFile(t *testing.T) {
+	ogOpts := Default().HandlerOptions()
+
+	t.Cleanup(func() {
+		Default().SetHandlerOptions(ogOpts)
+	})
+
+	path := filepath.Join(t.TempDir(), "flume.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("level: WRN"), 0o600))
+
+	require.NoError(t, ConfigFromFile(path))
+	assert.Equal(t, slog.LevelWarn, Default().HandlerOptions().Level)
+
+	require.NoError(t, os.WriteFile(path, []byte("level: ["), 0o600))
+
+	require.Error(t, ConfigFromFile(path))
+	assert.Equal(t, slog.LevelWarn, Default().HandlerOptions().Level, "should keep the options on error")
+
+	assert.Panics(t, func() {
+		MustConfigFromFile(path)
+	})
+}
+
+// syncBuffer is a bytes.Buffer which is safe to write from other goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// writeFileAtomic replaces the file with a rename, so a concurrent poll never reads a
+// partially written file.
+func writeFileAtomic(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path+".tmp", []byte(content), 0o600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flume.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte("level: WRN\nhandler: json"), 0o600))
+
+	buf := &syncBuffer{}
+	h := NewHandler(buf, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, watchConfigFile(ctx, h, path, time.Millisecond))
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level)
+
+	levelIs := func(lvl slog.Level) func() bool {
+		return func() bool {
+			return h.HandlerOptions().Level == lvl
+		}
+	}
+
+	writeFileAtomic(t, path, "level: INF\nhandler: json")
+	require.Eventually(t, levelIs(slog.LevelInfo), time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"msg":"configuration changed","logger":"flume","path":"`+path+`"`)
+	}, time.Second, time.Millisecond)
+
+	// parse errors keep the last good options
+	writeFileAtomic(t, path, "level: [")
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"msg":"failed to parse configuration file, keeping the last configuration"`)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, slog.LevelInfo, h.HandlerOptions().Level)
+
+	// simulate a ConfigMap update, which swaps a symlink to a new directory
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "v2"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v2", "flume.yaml"), []byte("level: ERR"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "v2", "flume.yaml"), path+".tmp"))
+	require.NoError(t, os.Rename(path+".tmp", path))
+	require.Eventually(t, levelIs(slog.LevelError), time.Second, time.Millisecond)
+
+	// changes after ctx is done are ignored
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v2", "flume.yaml"), []byte("level: DBG"), 0o600))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, h.HandlerOptions().Level)
+}
+
+func TestWatchConfigFile_keepsOptionsSetInCode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flume.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("level: WRN"), 0o600))
+
+	buf := &syncBuffer{}
+	h := NewHandler(buf, &HandlerOptions{
+		Level:        slog.LevelError,
+		HandlerFn:    JSONHandlerFn(),
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+		Middleware: []Middleware{SimpleMiddlewareFn(func(ctx context.Context, record slog.Record, next slog.Handler) error {
+			record.AddAttrs(slog.String("app", "billing"))
+			return next.Handle(ctx, record)
+		})},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, watchConfigFile(ctx, h, path, time.Millisecond))
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level)
+
+	writeFileAtomic(t, path, "levels: http=DBG")
+	require.Eventually(t, func() bool {
+		return h.HandlerOptions().Levels["http"] == slog.LevelDebug
+	}, time.Second, time.Millisecond)
+
+	opts := h.HandlerOptions()
+	assert.Equal(t, slog.LevelError, opts.Level, "removing a property from the file should restore the option set in code")
+	assert.Len(t, opts.Middleware, 1)
+	assert.Len(t, opts.ReplaceAttrs, 1)
+
+	cancel()
+
+	slog.New(h).Error("hi")
+	assert.Contains(t, buf.String(), `{"level":"ERROR","msg":"hi","app":"billing"}`)
+}
+
+func TestWatchConfigFile_initialError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flume.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	h := NewHandler(nil, &HandlerOptions{Level: slog.LevelWarn})
+
+	require.Error(t, watchConfigFile(context.Background(), h, path, time.Millisecond))
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level)
+}
@@ -0,0 +1,214 @@
+package flume
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+)
+
+// DuplicateKeyPolicy selects how DedupeMiddleware resolves attrs with the same key.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysLastWins keeps the value of the last attr with the key, in the
+	// position of the first.
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeysFirstWins keeps the first attr with the key, and drops the rest.
+	DuplicateKeysFirstWins
+	// DuplicateKeysRename keeps all the attrs, renaming duplicates by adding a suffix
+	// to the key, i.e. "error", "error_2", "error_3".
+	DuplicateKeysRename
+	// DuplicateKeysCollect replaces the attrs with a single attr, in the position of the
+	// first, whose value is a list of all the values, in order.  Groups are collected
+	// as map[string]any.
+	DuplicateKeysCollect
+)
+
+// DedupeMiddleware returns middleware which resolves attrs with duplicate keys, according
+// to policy.  slog handlers emit all attrs, so the same key can appear several times in
+// the output, i.e. if an attr is added with Logger.With, and again when logging.
+//
+// Keys are compared within each group, including groups opened with WithGroup, and
+// attrs added with WithAttrs are compared with the attrs of each record.  Attrs in
+// groups with empty keys are inlined before comparing.  If two groups have the same
+// key, the policy is applied to the groups as a whole: their members are not merged.
+//
+// To compare attrs added with WithAttrs, the middleware holds them, and the open groups,
+// until a record is handled, instead of passing them to the next handler.  Middleware
+// after this one which tracks the logger name, like Metrics, won't see the name, so
+// should come before it.
+func DedupeMiddleware(policy DuplicateKeyPolicy) Middleware {
+	return MiddlewareFn(func(next slog.Handler) slog.Handler {
+		return &dedupeHandler{policy: policy, next: next, attrs: make([][]slog.Attr, 1)}
+	})
+}
+
+type dedupeHandler struct {
+	policy DuplicateKeyPolicy
+	next   slog.Handler
+
+	// open groups
+	groups []string
+	// attrs added with WithAttrs.  attrs[0] are at the root, and attrs[i] are in groups[i-1]
+	attrs [][]slog.Attr
+}
+
+func (h *dedupeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *dedupeHandler) Handle(ctx context.Context, record slog.Record) error {
+	last := len(h.groups)
+
+	attrs := slices.Clip(h.attrs[last])
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	// nest the attrs in the open groups, innermost first
+	for i := last - 1; i >= 0; i-- {
+		parent := slices.Clip(h.attrs[i])
+		if len(attrs) > 0 {
+			parent = append(parent, slog.Attr{Key: h.groups[i], Value: slog.GroupValue(attrs...)})
+		}
+
+		attrs = parent
+	}
+
+	deduped := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	deduped.AddAttrs(h.dedupe(attrs)...)
+
+	return h.next.Handle(ctx, deduped)
+}
+
+func (h *dedupeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := h.clone()
+
+	last := len(h2.groups)
+	h2.attrs[last] = append(slices.Clip(h2.attrs[last]), attrs...)
+
+	return h2
+}
+
+func (h *dedupeHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	h2.attrs = append(h2.attrs, nil)
+
+	return h2
+}
+
+func (h *dedupeHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.next)
+}
+
+func (h *dedupeHandler) clone() *dedupeHandler {
+	return &dedupeHandler{
+		policy: h.policy,
+		next:   h.next,
+		groups: slices.Clip(h.groups),
+		attrs:  slices.Clone(h.attrs),
+	}
+}
+
+// dedupe returns attrs with duplicate keys resolved, recursing into groups.
+func (h *dedupeHandler) dedupe(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+	index := make(map[string]int, len(attrs))
+
+	var collected map[string]bool
+
+	for _, a := range inlineGroups(attrs, nil) {
+		if a.Value.Kind() == slog.KindGroup {
+			members := h.dedupe(a.Value.Group())
+			if len(members) == 0 {
+				// handlers omit empty groups
+				continue
+			}
+
+			a.Value = slog.GroupValue(members...)
+		}
+
+		i, seen := index[a.Key]
+		if !seen {
+			index[a.Key] = len(out)
+			out = append(out, a)
+
+			continue
+		}
+
+		switch h.policy {
+		case DuplicateKeysFirstWins:
+		case DuplicateKeysRename:
+			for n := 2; ; n++ {
+				key := a.Key + "_" + strconv.Itoa(n)
+				if _, ok := index[key]; !ok {
+					a.Key = key
+					index[key] = len(out)
+					out = append(out, a)
+
+					break
+				}
+			}
+		case DuplicateKeysCollect:
+			if collected == nil {
+				collected = map[string]bool{}
+			}
+
+			if collected[a.Key] {
+				values, _ := out[i].Value.Any().([]any)
+				out[i].Value = slog.AnyValue(append(values, collectedValue(a.Value)))
+			} else {
+				collected[a.Key] = true
+				out[i].Value = slog.AnyValue([]any{collectedValue(out[i].Value), collectedValue(a.Value)})
+			}
+		default:
+			out[i].Value = a.Value
+		}
+	}
+
+	return out
+}
+
+// collectedValue returns the value to add to a list of collected values.  Handlers
+// render groups nested in lists as raw []slog.Attr, so groups are converted to maps.
+func collectedValue(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+
+	m := make(map[string]any, len(v.Group()))
+	for _, a := range v.Group() {
+		m[a.Key] = collectedValue(a.Value)
+	}
+
+	return m
+}
+
+// inlineGroups appends attrs to dst, resolving values, replacing groups with empty
+// keys with their members, and dropping empty attrs.
+func inlineGroups(attrs []slog.Attr, dst []slog.Attr) []slog.Attr {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		switch {
+		case a.Equal(slog.Attr{}):
+		case a.Key == "" && a.Value.Kind() == slog.KindGroup:
+			dst = inlineGroups(a.Value.Group(), dst)
+		default:
+			dst = append(dst, a)
+		}
+	}
+
+	return dst
+}
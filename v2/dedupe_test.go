@@ -0,0 +1,115 @@
+package flume
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateKeyPolicy
+		log    func(l *slog.Logger)
+		want   string
+	}{
+		{
+			name:   "no duplicates",
+			policy: DuplicateKeysLastWins,
+			log: func(l *slog.Logger) {
+				l.With("a", 1).Info("hi", "b", 2)
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"b":2}`,
+		},
+		{
+			name:   "last wins",
+			policy: DuplicateKeysLastWins,
+			log: func(l *slog.Logger) {
+				l.With("a", 1, "b", 2).Info("hi", "a", 3)
+			},
+			want: `{"level":"INFO","msg":"hi","a":3,"b":2}`,
+		},
+		{
+			name:   "first wins",
+			policy: DuplicateKeysFirstWins,
+			log: func(l *slog.Logger) {
+				l.With("a", 1, "b", 2).Info("hi", "a", 3)
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"b":2}`,
+		},
+		{
+			name:   "rename",
+			policy: DuplicateKeysRename,
+			log: func(l *slog.Logger) {
+				l.With("a", 1, "a_2", 2).Info("hi", "a", 3, "a", 4)
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"a_2":2,"a_3":3,"a_4":4}`,
+		},
+		{
+			name:   "collect",
+			policy: DuplicateKeysCollect,
+			log: func(l *slog.Logger) {
+				l.With("a", 1).Info("hi", "a", "two", "b", true, "a", 3)
+			},
+			want: `{"level":"INFO","msg":"hi","a":[1,"two",3],"b":true}`,
+		},
+		{
+			name:   "collect groups",
+			policy: DuplicateKeysCollect,
+			log: func(l *slog.Logger) {
+				l.Info("hi", slog.Group("g", "a", 1), "g", 2, slog.Group("g", "b", "x", slog.Group("c", "d", true)))
+			},
+			want: `{"level":"INFO","msg":"hi","g":[{"a":1},2,{"b":"x","c":{"d":true}}]}`,
+		},
+		{
+			name:   "groups",
+			policy: DuplicateKeysLastWins,
+			log: func(l *slog.Logger) {
+				l.With("a", 1).WithGroup("req").With("a", 2, "id", 1).Info("hi", "id", 2, slog.Group("g", "x", 1, "x", 2))
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"req":{"a":2,"id":2,"g":{"x":2}}}`,
+		},
+		{
+			name:   "inline groups",
+			policy: DuplicateKeysFirstWins,
+			log: func(l *slog.Logger) {
+				l.With("a", 1).Info("hi", slog.Group("", "a", 2, "b", 3))
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"b":3}`,
+		},
+		{
+			name:   "empty open groups",
+			policy: DuplicateKeysLastWins,
+			log: func(l *slog.Logger) {
+				l.WithGroup("req").Info("hi")
+			},
+			want: `{"level":"INFO","msg":"hi"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := DedupeMiddleware(tt.policy).Apply(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeKeys(slog.TimeKey)}))
+
+			tt.log(slog.New(h))
+
+			assert.JSONEq(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestDedupeMiddleware_loggerName(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	h := NewHandler(buf, &HandlerOptions{
+		HandlerFn:    JSONHandlerFn(),
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+		Middleware:   []Middleware{DedupeMiddleware(DuplicateKeysLastWins)},
+	})
+
+	slog.New(h.Named("http")).With(LoggerKey, "api").Info("hi")
+
+	assert.JSONEq(t, `{"level":"INFO","msg":"hi","logger":"api"}`, buf.String())
+}
@@ -11,8 +11,13 @@ const (
 	LevelInfo  = slog.LevelInfo
 	LevelWarn  = slog.LevelWarn
 	LevelError = slog.LevelError
-	LevelOff   = slog.Level(math.MaxInt)
-	LevelAll   = slog.Level(math.MinInt)
+	// LevelTrace, LevelNotice, and LevelFatal are custom levels, registered with
+	// RegisterLevel by default.
+	LevelTrace  = slog.Level(-8)
+	LevelNotice = slog.Level(2)
+	LevelFatal  = slog.Level(12)
+	LevelOff    = slog.Level(math.MaxInt)
+	LevelAll    = slog.Level(math.MinInt)
 )
 
 // New is a convenience for creating a named logger using the default handler.
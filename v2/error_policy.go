@@ -0,0 +1,129 @@
+package flume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultErrorReportInterval is the default ErrorPolicy.ReportInterval.
+const DefaultErrorReportInterval = time.Minute
+
+// ErrorPolicy configures how errors returned by sink handlers are reported.  slog.Logger
+// discards the errors returned by its handler, so without a policy, a broken pipe or a full
+// disk silently loses all logs.
+//
+// An ErrorPolicy counts errors across all the sinks of a Handler, and across calls to
+// SetHandlerOptions, so it must not be copied after first use.
+type ErrorPolicy struct {
+	// OnError, if set, is called with every error returned by a sink, and the record
+	// which failed.  It is called synchronously, so it should be fast, and must not
+	// log to the failing handler.
+	OnError func(ctx context.Context, err error, record slog.Record)
+
+	// Fallback, if set, receives a one line report of sink errors, like
+	// "flume: failed to write log record: write /var/log/app.log: no space left on device",
+	// at most once per ReportInterval.  Typically os.Stderr.
+	Fallback io.Writer
+
+	// ReportInterval limits how often errors are reported to Fallback.  Errors which
+	// occur within the interval after a report are counted, and the count is included in
+	// the next report.  Defaults to DefaultErrorReportInterval.
+	ReportInterval time.Duration
+
+	errors  atomic.Uint64
+	dropped atomic.Uint64
+
+	mu         sync.Mutex
+	lastReport time.Time
+	suppressed uint64
+}
+
+// Errors returns the number of errors returned by sinks, including dropped records.
+func (p *ErrorPolicy) Errors() uint64 {
+	return p.errors.Load()
+}
+
+// Dropped returns the number of records sinks reported as dropped, with errors wrapping
+// ErrDropped.
+func (p *ErrorPolicy) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+func (p *ErrorPolicy) handleError(ctx context.Context, err error, record slog.Record) {
+	p.errors.Add(1)
+
+	if errors.Is(err, ErrDropped) {
+		p.dropped.Add(1)
+	}
+
+	if p.OnError != nil {
+		p.OnError(ctx, err, record)
+	}
+
+	if p.Fallback != nil {
+		p.report(err)
+	}
+}
+
+func (p *ErrorPolicy) report(err error) {
+	interval := p.ReportInterval
+	if interval <= 0 {
+		interval = DefaultErrorReportInterval
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	if !p.lastReport.IsZero() && now.Sub(p.lastReport) < interval {
+		p.suppressed++
+		return
+	}
+
+	msg := "flume: failed to write log record: " + err.Error()
+	if p.suppressed > 0 {
+		msg += fmt.Sprintf(" (%d more errors since last report)", p.suppressed)
+	}
+
+	p.lastReport = now
+	p.suppressed = 0
+
+	_, _ = io.WriteString(p.Fallback, msg+"\n")
+}
+
+type errorPolicyHandler struct {
+	policy *ErrorPolicy
+	next   slog.Handler
+}
+
+func (h *errorPolicyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *errorPolicyHandler) Handle(ctx context.Context, record slog.Record) error {
+	err := h.next.Handle(ctx, record)
+	if err != nil {
+		h.policy.handleError(ctx, err, record)
+	}
+
+	return err
+}
+
+func (h *errorPolicyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorPolicyHandler{policy: h.policy, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *errorPolicyHandler) WithGroup(name string) slog.Handler {
+	return &errorPolicyHandler{policy: h.policy, next: h.next.WithGroup(name)}
+}
+
+func (h *errorPolicyHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.next)
+}
@@ -0,0 +1,129 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	return len(p), nil
+}
+
+func TestErrorPolicy(t *testing.T) {
+	w := &failingWriter{err: syscall.ENOSPC}
+	fallback := bytes.NewBuffer(nil)
+
+	var failed []string
+
+	policy := &ErrorPolicy{
+		OnError: func(_ context.Context, err error, record slog.Record) {
+			require.ErrorIs(t, err, syscall.ENOSPC)
+
+			failed = append(failed, record.Message)
+		},
+		Fallback:       fallback,
+		ReportInterval: time.Hour,
+	}
+
+	h := NewHandler(w, &HandlerOptions{ErrorPolicy: policy})
+	l := slog.New(h)
+
+	require.ErrorIs(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "one", 0)), syscall.ENOSPC,
+		"errors should still be returned")
+
+	l.Info("two")
+	slog.New(h.Named("http")).WithGroup("req").Info("three")
+
+	w.err = nil
+
+	l.Info("four")
+
+	assert.Equal(t, []string{"one", "two", "three"}, failed)
+	assert.Equal(t, uint64(3), policy.Errors())
+	assert.Equal(t, uint64(0), policy.Dropped())
+
+	assert.Equal(t, "flume: failed to write log record: no space left on device\n", fallback.String(),
+		"errors after the first should be suppressed until the report interval elapses")
+
+	// the policy survives changes to the options
+	h.SetHandlerOptions(h.HandlerOptions())
+
+	w.err = errors.New("broken pipe")
+	policy.ReportInterval = time.Nanosecond
+
+	time.Sleep(time.Millisecond)
+
+	failed = nil
+	policy.OnError = nil
+
+	l.Info("five")
+
+	assert.Equal(t, uint64(4), policy.Errors())
+	assert.Equal(t, "flume: failed to write log record: no space left on device\n"+
+		"flume: failed to write log record: broken pipe (2 more errors since last report)\n", fallback.String())
+}
+
+func TestErrorPolicy_dropped(t *testing.T) {
+	policy := &ErrorPolicy{}
+
+	h := NewHandler(nil, &HandlerOptions{
+		ErrorPolicy: policy,
+		Middleware: []Middleware{SimpleMiddlewareFn(func(_ context.Context, _ slog.Record, _ slog.Handler) error {
+			return ErrOTLPQueueFull
+		})},
+	})
+
+	slog.New(h).Info("hi")
+
+	assert.Equal(t, uint64(1), policy.Errors())
+	assert.Equal(t, uint64(0), policy.Dropped())
+
+	h.SetHandlerOptions(&HandlerOptions{
+		ErrorPolicy: policy,
+		Middleware: []Middleware{SimpleMiddlewareFn(func(_ context.Context, _ slog.Record, _ slog.Handler) error {
+			return ErrDropped
+		})},
+	})
+
+	slog.New(h).Info("hi")
+
+	assert.Equal(t, uint64(2), policy.Errors())
+	assert.Equal(t, uint64(1), policy.Dropped())
+}
+
+func TestHandlerOptions_UnmarshalJSON_errorFallback(t *testing.T) {
+	var opts HandlerOptions
+
+	require.NoError(t, json.Unmarshal([]byte(`{"errorFallback":"stderr"}`), &opts))
+	require.NotNil(t, opts.ErrorPolicy)
+	assert.Equal(t, os.Stderr, opts.ErrorPolicy.Fallback)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"errorFallback":"stdout"}`), &opts))
+	require.NotNil(t, opts.ErrorPolicy)
+	assert.Equal(t, os.Stdout, opts.ErrorPolicy.Fallback)
+
+	err := json.Unmarshal([]byte(`{"errorFallback":"syslog"}`), &opts)
+	require.ErrorIs(t, err, ErrInvalidErrorFallback)
+	assert.EqualError(t, err, "invalid error fallback: 'syslog': must be stderr or stdout")
+
+	clone := opts.Clone()
+	assert.Same(t, opts.ErrorPolicy, clone.ErrorPolicy, "clones should share the policy")
+}
@@ -0,0 +1,204 @@
+package flume
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+
+	"github.com/ansel1/merry/v2"
+)
+
+// maxErrorDepth limits how deep StructuredError follows the chain of wrapped errors, in case
+// an error wraps itself.
+const maxErrorDepth = 32
+
+// StructuredErrors is a ReplaceAttr function which renders error values as a group,
+// so they are rendered consistently by all handlers.  See StructuredError for the
+// members of the group.
+//
+// Example:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+//		ReplaceAttr: StructuredErrors,
+//	}))
+//
+//	logger.Error("request failed", "error", fmt.Errorf("fetching user: %w", io.ErrUnexpectedEOF))
+//	// Output will be:
+//	// {"level":"ERROR","msg":"request failed","error":{"msg":"fetching user: unexpected EOF","type":"*fmt.wrapError",
+//	//   "cause":{"msg":"unexpected EOF","type":"*errors.errorString"}}}
+func StructuredErrors(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+
+	if err, ok := a.Value.Any().(error); ok {
+		a.Value = errorValue(err, 0)
+	}
+
+	return a
+}
+
+// StructuredError returns a slog.LogValuer which renders err as a group.  It can be used
+// to render a single error as a group, instead of installing the StructuredErrors
+// ReplaceAttr function:
+//
+//	logger.Error("request failed", "error", flume.StructuredError(err))
+//
+// The group has these members:
+//
+//   - "msg": err.Error()
+//   - "type": the Go type of err, like "*fs.PathError"
+//   - "stack": the stack where the error was created, if err records one, as a Stack
+//   - "details": the error formatted with "%+v", if err implements fmt.Formatter, doesn't
+//     record a stack flume can read, and the details differ from the message
+//   - "cause": the error returned by err.Unwrap() error, rendered as a group
+//   - "causes": the errors returned by err.Unwrap() []error, like errors created by
+//     errors.Join, rendered as a group of groups keyed by index
+//
+// Stacks are read from errors which implement Callers() []uintptr, or Stack() []uintptr,
+// like go-errors errors, and from merry errors with merry.Stack.  The layers merry wraps
+// around an error, one for each value attached to it, are rendered as a single error,
+// with the type of the error merry wrapped, and the cause set with merry.WithCause.
+func StructuredError(err error) slog.LogValuer {
+	return structuredError{err: err}
+}
+
+type structuredError struct {
+	err error
+}
+
+func (e structuredError) LogValue() slog.Value {
+	if e.err == nil {
+		return slog.AnyValue(nil)
+	}
+
+	return errorValue(e.err, 0)
+}
+
+func errorValue(err error, depth int) slog.Value {
+	// like slog, render nil pointers as "<nil>", instead of calling their Error method
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+		return slog.StringValue("<nil>")
+	}
+
+	if isMerryError(err) {
+		return merryErrorValue(err, depth)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("msg", err.Error()),
+		slog.String("type", fmt.Sprintf("%T", err)),
+	}
+
+	if stack := errorStack(err); len(stack) > 0 {
+		attrs = append(attrs, slog.Any("stack", stack))
+	} else if _, ok := err.(fmt.Formatter); ok {
+		if details := fmt.Sprintf("%+v", err); details != err.Error() {
+			attrs = append(attrs, slog.String("details", details))
+		}
+	}
+
+	return slog.GroupValue(appendErrorCauses(attrs, err, depth)...)
+}
+
+// appendErrorCauses appends the errors wrapped by err to attrs, as "cause" or "causes".
+func appendErrorCauses(attrs []slog.Attr, err error, depth int) []slog.Attr {
+	if depth >= maxErrorDepth {
+		return attrs
+	}
+
+	//nolint:errorlint
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := u.Unwrap(); cause != nil {
+			attrs = append(attrs, slog.Attr{Key: "cause", Value: errorValue(cause, depth+1)})
+		}
+	case interface{ Unwrap() []error }:
+		var causes []slog.Attr
+
+		for _, cause := range u.Unwrap() {
+			if cause != nil {
+				causes = append(causes, slog.Attr{Key: strconv.Itoa(len(causes)), Value: errorValue(cause, depth+1)})
+			}
+		}
+
+		if len(causes) > 0 {
+			attrs = append(attrs, slog.Attr{Key: "causes", Value: slog.GroupValue(causes...)})
+		}
+	}
+
+	return attrs
+}
+
+// merryPkgPath is the package of merry's error types.
+const merryPkgPath = "github.com/ansel1/merry/v2"
+
+// isMerryError reports whether err is one of merry's wrapper types.
+func isMerryError(err error) bool {
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.PkgPath() == merryPkgPath
+}
+
+// merryErrorValue renders a merry error.  merry wraps an error in a layer for each value
+// attached to it, like the stack and the message, which are collapsed into a single
+// group, with the type of the error merry wrapped, and the stack read with merry.Stack.
+func merryErrorValue(err error, depth int) slog.Value {
+	cause := merry.Cause(err)
+
+	// skip merry's layers, to the error they wrap
+	inner := err
+	for isMerryError(inner) {
+		// the layer holding the cause unwraps to the cause, but its As method
+		// returns the error it wraps
+		var wrapped error
+		if a, ok := inner.(interface{ As(target any) bool }); ok && a.As(&wrapped) && wrapped != nil {
+			inner = wrapped
+			continue
+		}
+
+		next := errors.Unwrap(inner)
+		if next == nil || next == cause { //nolint:errorlint
+			break
+		}
+
+		inner = next
+	}
+
+	attrs := []slog.Attr{
+		slog.String("msg", err.Error()),
+		slog.String("type", fmt.Sprintf("%T", inner)),
+	}
+
+	if stack := StackFromPCs(merry.Stack(err)); len(stack) > 0 {
+		attrs = append(attrs, slog.Any("stack", stack))
+	}
+
+	switch {
+	case cause != nil && depth < maxErrorDepth:
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: errorValue(cause, depth+1)})
+	case !isMerryError(inner):
+		attrs = appendErrorCauses(attrs, inner, depth)
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// errorStack returns the stack recorded by err, or nil.  Wrapped errors aren't searched:
+// each error in the chain renders its own stack.
+func errorStack(err error) Stack {
+	//nolint:errorlint
+	switch e := err.(type) {
+	case interface{ Callers() []uintptr }:
+		return StackFromPCs(e.Callers())
+	case interface{ Stack() []uintptr }:
+		return StackFromPCs(e.Stack())
+	}
+
+	return nil
+}
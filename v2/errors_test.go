@@ -0,0 +1,246 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/ansel1/merry/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 32)
+
+	return &stackError{msg: msg, pcs: pcs[:runtime.Callers(2, pcs)]}
+}
+
+func (e *stackError) Error() string {
+	return e.msg
+}
+
+func (e *stackError) Callers() []uintptr {
+	return e.pcs
+}
+
+func TestStructuredErrors(t *testing.T) {
+	err := fmt.Errorf("fetching user: %w", &fs.PathError{Op: "open", Path: "user.json", Err: fs.ErrNotExist})
+
+	tests := []struct {
+		name      string
+		handlerFn HandlerFn
+		want      string
+	}{
+		{
+			name:      "text",
+			handlerFn: TextHandlerFn(),
+			want: `level=INFO msg=failed logger=app error.msg="fetching user: open user.json: file does not exist" error.type=*fmt.wrapError` +
+				` error.cause.msg="open user.json: file does not exist" error.cause.type=*fs.PathError` +
+				` error.cause.cause.msg="file does not exist" error.cause.cause.type=*errors.errorString` + "\n",
+		},
+		{
+			name:      "term",
+			handlerFn: TermHandlerFn(),
+			want: `app      |INF| failed error.msg=fetching user: open user.json: file does not exist error.type=*fmt.wrapError` +
+				` error.cause.msg=open user.json: file does not exist error.cause.type=*fs.PathError` +
+				` error.cause.cause.msg=file does not exist error.cause.cause.type=*errors.errorString` + "\n",
+		},
+		{
+			name:      "json",
+			handlerFn: JSONHandlerFn(),
+			want: `{"level":"INFO","msg":"failed","logger":"app","error":{"msg":"fetching user: open user.json: file does not exist","type":"*fmt.wrapError",` +
+				`"cause":{"msg":"open user.json: file does not exist","type":"*fs.PathError",` +
+				`"cause":{"msg":"file does not exist","type":"*errors.errorString"}}}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := NewHandler(buf, &HandlerOptions{
+				HandlerFn:    tt.handlerFn,
+				ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), StructuredErrors},
+			})
+			l := slog.New(h.Named("app"))
+
+			l.Info("failed", "error", err)
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestStructuredError(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeKeys(slog.TimeKey)}))
+
+	joined := errors.Join(errors.New("first"), nil, &detailError{msg: "second", detail: " at line 2"})
+
+	l.Info("failed", "error", StructuredError(joined), "nil", StructuredError(nil))
+
+	assert.JSONEq(t, `{
+		"level":"INFO",
+		"msg":"failed",
+		"nil":null,
+		"error":{
+			"msg":"first\nsecond",
+			"type":"*errors.joinError",
+			"causes":{
+				"0":{"msg":"first","type":"*errors.errorString"},
+				"1":{"msg":"second","type":"*flume.detailError","details":"second at line 2"}
+			}
+		}
+	}`, buf.String())
+}
+
+func TestStructuredErrors_nilPointer(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			return StructuredErrors(groups, removeKeys(slog.TimeKey)(groups, a))
+		},
+	}))
+
+	var nilErr error = (*stackError)(nil)
+
+	l.Info("failed", "error", nilErr, "wrapped", &cyclicError{cause: nilErr}, "structured", StructuredError(nilErr))
+
+	assert.JSONEq(t, `{
+		"level":"INFO",
+		"msg":"failed",
+		"error":"<nil>",
+		"wrapped":{"msg":"cycle","type":"*flume.cyclicError","cause":"<nil>"},
+		"structured":"<nil>"
+	}`, buf.String())
+}
+
+func TestStructuredError_stack(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+
+	_, file, line, _ := runtime.Caller(0)
+	err := newStackError("boom")
+
+	l.Info("failed", "error", StructuredError(err))
+
+	var m struct {
+		Error struct {
+			Msg   string `json:"msg"`
+			Stack Stack  `json:"stack"`
+		} `json:"error"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+
+	assert.Equal(t, "boom", m.Error.Msg)
+	require.NotEmpty(t, m.Error.Stack)
+	assert.Equal(t, StackFrame{Function: "github.com/ThalesGroup/flume/v2.TestStructuredError_stack", File: file, Line: line + 1}, m.Error.Stack[0])
+
+	// in text, the stack renders one frame per line
+	buf.Reset()
+
+	l = slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeKeys(slog.TimeKey)}))
+	l.Info("failed", "error", StructuredError(err))
+
+	assert.Contains(t, buf.String(), fmt.Sprintf(`error.stack="github.com/ThalesGroup/flume/v2.TestStructuredError_stack %s:%d\n`, file, line+1))
+}
+
+func TestStructuredError_merry(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+
+	_, file, line, _ := runtime.Caller(0)
+	err := merry.Wrap(fmt.Errorf("fetching user: %w", fs.ErrNotExist), merry.WithHTTPCode(404), merry.AppendMessage("user 5"))
+
+	l.Info("failed", "error", StructuredError(err))
+
+	var m struct {
+		Error map[string]json.RawMessage `json:"error"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+
+	// merry's wrappers are collapsed into a single error, with the stack read from merry
+	assert.ElementsMatch(t, []string{"msg", "type", "stack", "cause"}, slices.Collect(maps.Keys(m.Error)))
+	assert.JSONEq(t, `"fetching user: file does not exist: user 5"`, string(m.Error["msg"]))
+	assert.JSONEq(t, `"*fmt.wrapError"`, string(m.Error["type"]))
+	assert.JSONEq(t, `{"msg":"file does not exist","type":"*errors.errorString"}`, string(m.Error["cause"]))
+
+	var stack Stack
+
+	require.NoError(t, json.Unmarshal(m.Error["stack"], &stack))
+	require.NotEmpty(t, stack)
+	assert.Equal(t, StackFrame{Function: "github.com/ThalesGroup/flume/v2.TestStructuredError_merry", File: file, Line: line + 1}, stack[0])
+
+	// wrapping a merry error with a cause
+	buf.Reset()
+
+	err = merry.New("saving user", merry.WithCause(err), merry.NoCaptureStack())
+
+	l.Info("failed", "error", StructuredError(fmt.Errorf("request failed: %w", err)))
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+
+	var cause map[string]json.RawMessage
+
+	require.NoError(t, json.Unmarshal(m.Error["cause"], &cause))
+	assert.JSONEq(t, `"saving user"`, string(cause["msg"]))
+	assert.JSONEq(t, `"*errors.errorString"`, string(cause["type"]))
+	assert.NotContains(t, cause, "stack")
+	assert.NotContains(t, cause, "details")
+
+	require.NoError(t, json.Unmarshal(cause["cause"], &cause))
+	assert.JSONEq(t, `"fetching user: file does not exist: user 5"`, string(cause["msg"]))
+	assert.Contains(t, cause, "stack")
+}
+
+func TestStructuredError_cycle(t *testing.T) {
+	err := &cyclicError{}
+	err.cause = err
+
+	v := StructuredError(err).LogValue()
+
+	depth := 0
+	for ; ; depth++ {
+		var cause slog.Value
+
+		for _, a := range v.Group() {
+			if a.Key == "cause" {
+				cause = a.Value
+			}
+		}
+
+		if cause.Kind() != slog.KindGroup {
+			break
+		}
+
+		v = cause
+	}
+
+	assert.Equal(t, maxErrorDepth, depth)
+}
+
+type cyclicError struct {
+	cause error
+}
+
+func (e *cyclicError) Error() string {
+	return "cycle"
+}
+
+func (e *cyclicError) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,80 @@
+package flume
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+)
+
+// ExitFunc is called by Fatal and FatalContext to exit the program, after the record has
+// been logged and the handler flushed.  Tests can replace it to intercept calls to Fatal.
+var ExitFunc = os.Exit
+
+// FatalFlushTimeout limits how long Fatal and Panic wait for the handler to flush.
+var FatalFlushTimeout = 5 * time.Second
+
+// Fatal logs a record at LevelFatal, flushes the logger's handler, then calls
+// ExitFunc(1).  If l is nil, it logs to the default handler.
+//
+// The handler is flushed if it implements Flusher.  Loggers created from a *Handler,
+// i.e. with New, flush all the Handler's sinks.
+func Fatal(l *slog.Logger, msg string, args ...any) {
+	logAndFlush(context.Background(), l, msg, args)
+	ExitFunc(1)
+}
+
+// FatalContext is like Fatal, but passes ctx to the handler.
+func FatalContext(ctx context.Context, l *slog.Logger, msg string, args ...any) {
+	logAndFlush(ctx, l, msg, args)
+	ExitFunc(1)
+}
+
+// Panic logs a record at LevelFatal, flushes the logger's handler, then panics with
+// msg.  If l is nil, it logs to the default handler.
+func Panic(l *slog.Logger, msg string, args ...any) {
+	logAndFlush(context.Background(), l, msg, args)
+	panic(msg)
+}
+
+// PanicContext is like Panic, but passes ctx to the handler.
+func PanicContext(ctx context.Context, l *slog.Logger, msg string, args ...any) {
+	logAndFlush(ctx, l, msg, args)
+	panic(msg)
+}
+
+func logAndFlush(ctx context.Context, l *slog.Logger, msg string, args []any) {
+	if l == nil {
+		l = slog.New(Default())
+	}
+
+	h := l.Handler()
+
+	if h.Enabled(ctx, LevelFatal) {
+		// skip runtime.Callers, this function, and the exported function which
+		// called it, so the source is the caller's
+		var pcs [1]uintptr
+
+		runtime.Callers(3, pcs[:])
+
+		record := slog.NewRecord(time.Now(), LevelFatal, msg, pcs[0])
+		record.Add(args...)
+
+		err := h.Handle(ctx, record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flume: logging fatal record: %v\n", err)
+		}
+	}
+
+	if f, ok := h.(Flusher); ok {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), FatalFlushTimeout)
+		defer cancel()
+
+		err := f.Flush(flushCtx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flume: flushing handler: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package flume
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func interceptExit(t *testing.T) *int {
+	t.Helper()
+
+	code := -1
+	old := ExitFunc
+	ExitFunc = func(c int) { code = c }
+
+	t.Cleanup(func() { ExitFunc = old })
+
+	return &code
+}
+
+func TestFatal(t *testing.T) {
+	code := interceptExit(t)
+
+	// output is buffered, so it is only written if Fatal flushes the handler
+	buf := bytes.NewBuffer(nil)
+	bw := bufio.NewWriter(buf)
+
+	h := NewHandler(bw, &HandlerOptions{
+		AddSource:    true,
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), NameLevel},
+	})
+	l := slog.New(h.Named("app"))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	Fatal(l, "boom", "color", "red")
+
+	assert.Equal(t, 1, *code)
+	assert.Equal(t, "level=FATAL source="+file+":"+strconv.Itoa(line+2)+" msg=boom logger=app color=red\n", buf.String())
+}
+
+func TestFatalContext(t *testing.T) {
+	code := interceptExit(t)
+
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{
+		AddSource:    true,
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+	}))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	FatalContext(context.Background(), l, "boom")
+
+	assert.Equal(t, 1, *code)
+	assert.Equal(t, "level=ERROR+4 source="+file+":"+strconv.Itoa(line+2)+" msg=boom\n", buf.String())
+}
+
+func TestFatal_disabled(t *testing.T) {
+	code := interceptExit(t)
+
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{Level: LevelOff}))
+
+	Fatal(l, "boom")
+
+	assert.Equal(t, 1, *code, "should exit even if the level is disabled")
+	assert.Empty(t, buf.String())
+}
+
+func TestPanic(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	bw := bufio.NewWriter(buf)
+
+	l := slog.New(NewHandler(bw, &HandlerOptions{
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), NameLevel},
+	}))
+
+	require.PanicsWithValue(t, "boom", func() {
+		Panic(l, "boom", "color", "red")
+	})
+
+	assert.Equal(t, "level=FATAL msg=boom color=red\n", buf.String())
+
+	require.PanicsWithValue(t, "bang", func() {
+		PanicContext(context.Background(), l, "bang")
+	})
+}
+
+type flushCounter struct {
+	slog.Handler
+	flushes int
+}
+
+func (f *flushCounter) Flush(context.Context) error {
+	f.flushes++
+	return nil
+}
+
+func TestHandler_Flush(t *testing.T) {
+	sinks := map[string]*flushCounter{}
+
+	h := NewHandler(nil, &HandlerOptions{
+		HandlerFn: func(name string, _ io.Writer, _ *slog.HandlerOptions) slog.Handler {
+			sinks[name] = &flushCounter{Handler: noop}
+			return sinks[name]
+		},
+		Middleware: []Middleware{ReplaceAttrs(nil), SimpleMiddlewareFn(func(ctx context.Context, record slog.Record, next slog.Handler) error {
+			return next.Handle(ctx, record)
+		})},
+	})
+
+	_ = h.Named("http")
+
+	require.NoError(t, slog.New(h.WithGroup("g")).Handler().(Flusher).Flush(context.Background()))
+
+	assert.Equal(t, 1, sinks[""].flushes)
+	assert.Equal(t, 1, sinks["http"].flushes)
+}
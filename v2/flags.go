@@ -0,0 +1,259 @@
+package flume
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The values returned by LevelValue, LevelsValue, HandlerValue, and ConfigValue implement
+// flag.Value, and also have a Type() method, so they implement pflag.Value too, for use
+// with github.com/spf13/pflag and cobra.
+
+// LevelValue returns a flag.Value which parses a level into l, like the "level" property
+// of the json config, i.e. "INF", "WRN+1", "-4", or "OFF".
+//
+//	flag.Var(flume.LevelValue(&opts.Level), "log-level", "log level")
+func LevelValue(l *slog.Leveler) flag.Value {
+	return levelValue{l: l}
+}
+
+type levelValue struct {
+	l *slog.Leveler
+}
+
+func (v levelValue) String() string {
+	if v.l == nil || *v.l == nil {
+		return ""
+	}
+
+	return flagLevelString((*v.l).Level())
+}
+
+func (v levelValue) Set(s string) error {
+	l, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+
+	*v.l = l
+
+	return nil
+}
+
+func (v levelValue) Type() string {
+	return "level"
+}
+
+// LevelsValue returns a flag.Value which parses a levels string into l, like
+// "http=DBG,-sql".  See UnmarshalEnv for the syntax.  Unlike Levels.UnmarshalText, each
+// call to Set merges the parsed levels into l, so the flag can be repeated.
+func LevelsValue(l *Levels) flag.Value {
+	return levelsValue{l: l}
+}
+
+type levelsValue struct {
+	l *Levels
+}
+
+func (v levelsValue) String() string {
+	if v.l == nil {
+		return ""
+	}
+
+	return levelsString(*v.l)
+}
+
+func (v levelsValue) Set(s string) error {
+	levels, err := parseLevels(s)
+	if err != nil {
+		return err
+	}
+
+	if *v.l == nil {
+		*v.l = Levels{}
+	}
+
+	maps.Copy(*v.l, levels)
+
+	return nil
+}
+
+func (v levelsValue) Type() string {
+	return "levels"
+}
+
+// optionsLevelsValue is like levelsValue, but "*" sets the default level, like the
+// "levels" property of the json config.
+type optionsLevelsValue struct {
+	o *HandlerOptions
+}
+
+func (v optionsLevelsValue) String() string {
+	if v.o == nil {
+		return ""
+	}
+
+	return levelsString(v.o.Levels)
+}
+
+func (v optionsLevelsValue) Set(s string) error {
+	err := LevelsValue(&v.o.Levels).Set(s)
+	if err != nil {
+		return err
+	}
+
+	if lvl, ok := v.o.Levels["*"]; ok {
+		v.o.Level = lvl
+		delete(v.o.Levels, "*")
+	}
+
+	return nil
+}
+
+func (v optionsLevelsValue) Type() string {
+	return "levels"
+}
+
+// HandlerValue returns a flag.Value which looks up a registered handler by name, with
+// LookupHandlerFn, and stores it in fn.  Like the "handler" property of the json config.
+func HandlerValue(fn *HandlerFn) flag.Value {
+	return &handlerValue{fn: fn}
+}
+
+type handlerValue struct {
+	fn *HandlerFn
+	// the name of the handler, since a HandlerFn can't be mapped back to its name
+	name string
+}
+
+func (v *handlerValue) String() string {
+	return v.name
+}
+
+func (v *handlerValue) Set(s string) error {
+	fn := LookupHandlerFn(s)
+	if fn == nil {
+		return fmt.Errorf("%w: '%v'", ErrUnregisteredHandler, s)
+	}
+
+	*v.fn = fn
+	v.name = s
+
+	return nil
+}
+
+func (v *handlerValue) Type() string {
+	return "handler"
+}
+
+// ConfigValue returns a flag.Value which parses the full config into o.  Like the FLUME
+// environment variable, the value can be either json, or a levels string.  See
+// UnmarshalEnv.  Both are merged over o: a levels string replaces o's per-logger levels,
+// and sets the default level if it includes "*".
+func ConfigValue(o *HandlerOptions) flag.Value {
+	return &configValue{o: o}
+}
+
+type configValue struct {
+	o *HandlerOptions
+	// the last value set, since the options can't be rendered back into the same form
+	value string
+}
+
+func (v *configValue) String() string {
+	return v.value
+}
+
+func (v *configValue) Set(s string) error {
+	if strings.HasPrefix(s, "{") {
+		err := json.Unmarshal([]byte(s), v.o)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := setLevelsString(v.o, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	v.value = s
+
+	return nil
+}
+
+func (v *configValue) Type() string {
+	return "config"
+}
+
+// RegisterFlags defines the common logging flags in fs, which set fields of opts:
+//
+//	--log-level   the default level, i.e. "INF"
+//	--log-levels  per-logger levels, i.e. "http=DBG,-sql".  Can be repeated.
+//	--log-format  the name of a registered handler, i.e. "json" or "term-color"
+//	--log-source  add source locations to records
+//
+// If fs is nil, the flags are defined in flag.CommandLine.  After the flags are
+// parsed, apply the options, i.e. with Default().SetHandlerOptions(opts).
+func RegisterFlags(fs *flag.FlagSet, opts *HandlerOptions) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	fs.Var(LevelValue(&opts.Level), "log-level", "default log level, e.g. INF, DBG, WRN+1, or OFF")
+	fs.Var(optionsLevelsValue{o: opts}, "log-levels", "per-logger log levels, e.g. http=DBG,-sql (can be repeated)")
+	fs.Var(HandlerValue(&opts.HandlerFn), "log-format", "log format, one of: "+strings.Join(handlerNames(), ", "))
+	fs.BoolVar(&opts.AddSource, "log-source", opts.AddSource, "add source locations to log records")
+}
+
+// flagLevelString formats a level so it can be parsed by parseLevel.
+func flagLevelString(l slog.Level) string {
+	switch l {
+	case LevelOff:
+		return "OFF"
+	case LevelAll:
+		return "ALL"
+	default:
+		return formatLevel(l, false)
+	}
+}
+
+// levelsString formats levels as a levels string, sorted by logger name.
+func levelsString(l Levels) string {
+	directives := make([]string, 0, len(l))
+
+	for _, name := range slices.Sorted(maps.Keys(l)) {
+		switch lvl := l[name].Level(); lvl {
+		case LevelOff:
+			directives = append(directives, "-"+name)
+		case LevelAll:
+			directives = append(directives, name)
+		default:
+			directives = append(directives, name+"="+formatLevel(lvl, false))
+		}
+	}
+
+	return strings.Join(directives, ",")
+}
+
+// handlerNames returns the sorted names of the registered handlers.
+func handlerNames() []string {
+	initHandlerFns()
+
+	var names []string
+
+	handlerFns.Range(func(key, _ any) bool {
+		names = append(names, key.(string)) //nolint:forcetypeassert // only strings are stored
+
+		return true
+	})
+
+	slices.Sort(names)
+
+	return names
+}
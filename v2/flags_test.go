@@ -0,0 +1,160 @@
+package flume
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := HandlerOptions{Levels: Levels{"boot": LevelDebug}}
+
+	RegisterFlags(fs, &opts)
+
+	err := fs.Parse([]string{
+		"--log-level", "WRN+1",
+		"--log-levels", "http=DBG,-sql",
+		"--log-levels", "*=ERR,authz",
+		"--log-format", "json",
+		"--log-source",
+	})
+	require.NoError(t, err)
+
+	assertHandlerOptionsEqual(t, HandlerOptions{
+		Level:     LevelError,
+		Levels:    Levels{"boot": LevelDebug, "http": LevelDebug, "sql": LevelOff, "authz": LevelAll},
+		HandlerFn: JSONHandlerFn(),
+		AddSource: true,
+	}, opts, "")
+
+	assert.Equal(t, "ERROR", fs.Lookup("log-level").Value.String())
+	assert.Equal(t, "authz,boot=DEBUG,http=DEBUG,-sql", fs.Lookup("log-levels").Value.String())
+	assert.Equal(t, "json", fs.Lookup("log-format").Value.String())
+	assert.Equal(t, "true", fs.Lookup("log-source").Value.String())
+}
+
+func TestRegisterFlags_errors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"--log-level", "loud"},
+			want: `invalid value "loud" for flag -log-level: invalid log level 'loud'`,
+		},
+		{
+			args: []string{"--log-levels", "http=loud"},
+			want: `invalid value "http=loud" for flag -log-levels: invalid levels value 'http=loud'`,
+		},
+		{
+			args: []string{"--log-format", "xml"},
+			want: `invalid value "xml" for flag -log-format: unregistered handler: 'xml'`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.args[0], func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(bytes.NewBuffer(nil))
+
+			RegisterFlags(fs, &HandlerOptions{})
+
+			assert.ErrorContains(t, fs.Parse(test.args), test.want)
+		})
+	}
+}
+
+func TestRegisterFlags_usage(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	buf := bytes.NewBuffer(nil)
+	fs.SetOutput(buf)
+
+	RegisterFlags(fs, &HandlerOptions{})
+	fs.PrintDefaults()
+
+	assert.Contains(t, buf.String(), "log format, one of: cbor, gelf, journald, json, json-pretty, msgpack, noop, syslog, term, term-auto, term-color, text")
+	assert.NotContains(t, buf.String(), "(default", "zero values should not print defaults")
+}
+
+func TestLevelValue(t *testing.T) {
+	var l slog.Leveler
+
+	v := LevelValue(&l)
+	assert.Empty(t, v.String())
+
+	for _, s := range []string{"OFF", "ALL", "INFO", "NOTICE", "INFO+3", "DEBUG-5"} {
+		require.NoError(t, v.Set(s))
+		assert.Equal(t, s, v.String(), "should round trip")
+	}
+
+	require.NoError(t, v.Set("-4"))
+	assert.Equal(t, slog.LevelDebug, l)
+}
+
+func TestLevelsValue(t *testing.T) {
+	var l Levels
+
+	v := LevelsValue(&l)
+	assert.Empty(t, v.String())
+
+	require.NoError(t, v.Set("http=INF"))
+	require.NoError(t, v.Set("*=WRN,http=DBG"))
+
+	assert.Equal(t, Levels{"*": LevelWarn, "http": LevelDebug}, l, `"*" is only special in HandlerOptions`)
+	assert.Equal(t, "*=WARN,http=DEBUG", v.String())
+}
+
+func TestHandlerValue(t *testing.T) {
+	var fn HandlerFn
+
+	v := HandlerValue(&fn)
+	assert.Empty(t, v.String())
+
+	require.NoError(t, v.Set("text"))
+	assert.NotNil(t, fn)
+	assert.Equal(t, "text", v.String())
+
+	require.ErrorIs(t, v.Set("xml"), ErrUnregisteredHandler)
+	assert.Equal(t, "text", v.String())
+}
+
+func TestConfigValue(t *testing.T) {
+	opts := HandlerOptions{AddSource: true}
+
+	v := ConfigValue(&opts)
+
+	require.NoError(t, v.Set(`{"level":"WRN","handler":"json"}`))
+	assertHandlerOptionsEqual(t, HandlerOptions{Level: LevelWarn, HandlerFn: JSONHandlerFn(), AddSource: true}, opts, "")
+	assert.JSONEq(t, `{"level":"WRN","handler":"json"}`, v.String())
+
+	// levels strings are merged over the options too
+	require.NoError(t, v.Set("*=ERR,http"))
+	assertHandlerOptionsEqual(t, HandlerOptions{Level: LevelError, Levels: Levels{"http": LevelAll}, HandlerFn: JSONHandlerFn(), AddSource: true}, opts, "")
+
+	require.NoError(t, v.Set("sql=DBG"))
+	assertHandlerOptionsEqual(t, HandlerOptions{Level: LevelError, Levels: Levels{"sql": slog.LevelDebug}, HandlerFn: JSONHandlerFn(), AddSource: true}, opts, "")
+
+	require.ErrorIs(t, v.Set(`{"level":"loud"}`), ErrInvalidLevel)
+	require.ErrorIs(t, v.Set(`http=loud`), ErrInvalidLevels)
+}
+
+// pflagValue is the pflag.Value interface, which is flag.Value plus Type().
+type pflagValue interface {
+	flag.Value
+	Type() string
+}
+
+func TestFlagValues_pflag(t *testing.T) {
+	var opts HandlerOptions
+
+	assert.Implements(t, (*pflagValue)(nil), LevelValue(&opts.Level))
+	assert.Implements(t, (*pflagValue)(nil), LevelsValue(&opts.Levels))
+	assert.Implements(t, (*pflagValue)(nil), HandlerValue(&opts.HandlerFn))
+	assert.Implements(t, (*pflagValue)(nil), ConfigValue(&opts))
+}
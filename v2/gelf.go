@@ -0,0 +1,367 @@
+package flume
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GELFCompression selects the compression applied to GELF messages sent over UDP.
+type GELFCompression int
+
+const (
+	// GELFCompressNone sends uncompressed messages.
+	GELFCompressNone GELFCompression = iota
+	// GELFCompressGzip compresses messages with gzip.
+	GELFCompressGzip
+	// GELFCompressZlib compresses messages with zlib.
+	GELFCompressZlib
+)
+
+const (
+	// DefaultGELFChunkSize is the default maximum size of UDP datagrams sent
+	// by GELFConn.  It is small enough to pass through most networks unfragmented.
+	DefaultGELFChunkSize = 1420
+
+	gelfChunkHeaderLen = 12
+	gelfMaxChunks      = 128
+)
+
+// ErrGELFMessageTooLarge is returned when a GELF message doesn't fit in the
+// maximum number of UDP chunks.
+var ErrGELFMessageTooLarge = errors.New("gelf message too large")
+
+// ErrGELFConnClosed is returned when writing to a closed GELFConn.
+var ErrGELFConnClosed = errors.New("gelf connection closed")
+
+// GELFOptions configures NewGELFHandler and NewGELFHandlerFn.
+type GELFOptions struct {
+	// Level, AddSource, and ReplaceAttr work the same as
+	// in the slog handlers.
+	slog.HandlerOptions
+
+	// Host is the host field of each message.  Defaults to os.Hostname().
+	Host string
+
+	// Compression and ChunkSize configure the UDP connections created by NewGELFHandlerFn.
+	// See GELFConn.
+	Compression GELFCompression
+	ChunkSize   int
+}
+
+// NewGELFHandler returns a slog.Handler which formats records as GELF 1.1 messages, and
+// writes them to w, one message per call to Write.  To send messages to Graylog, w
+// should be a *GELFConn.  opts may be nil.
+//
+// Records are mapped to GELF fields:
+//
+//   - short_message: the first line of the record's message, or "-" if it's blank,
+//     since GELF requires a non-empty short_message
+//   - full_message: the whole message, if it isn't the same as short_message
+//   - timestamp: the record's time, in seconds since the epoch
+//   - level: the record's level, mapped to a syslog severity with SeverityForLevel
+//   - _logger: the value of the LoggerKey attr
+//
+// The remaining attrs are sent as additional fields, prefixed with "_".  Attrs in
+// groups are flattened, and the group names are prepended to the field name,
+// joined by "_", so the attr "method" in the group "req" becomes "_req_method".
+// Characters which aren't allowed in GELF field names are replaced with "_", and
+// the reserved field "_id" is renamed to "__id".  GELF field values must be strings
+// or numbers, so values other than numbers are rendered as strings.
+func NewGELFHandler(w io.Writer, opts *GELFOptions) slog.Handler {
+	var o GELFOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Host == "" {
+		o.Host, _ = os.Hostname()
+	}
+
+	return newObjectHandler(w, &o.HandlerOptions, func(buf *bytes.Buffer, record slog.Record, obj *attrObject) error {
+		o.encode(buf, record, obj)
+		return nil
+	})
+}
+
+// NewGELFHandlerFn returns a HandlerFn which sends GELF messages to the server at the
+// network address, using a single, shared GELFConn.  network may be "udp" or "tcp".
+// If network is empty, messages are written to the writer passed to the HandlerFn,
+// i.e. flume's output, terminated by newlines.
+//
+// The Level, AddSource, and ReplaceAttr options passed to the HandlerFn override
+// those in opts.
+func NewGELFHandlerFn(network, addr string, opts *GELFOptions) HandlerFn {
+	var conn *GELFConn
+
+	if network != "" {
+		conn = NewGELFConn(network, addr)
+
+		if opts != nil {
+			conn.Compression = opts.Compression
+
+			if opts.ChunkSize > 0 {
+				conn.ChunkSize = opts.ChunkSize
+			}
+		}
+	}
+
+	return func(_ string, w io.Writer, hOpts *slog.HandlerOptions) slog.Handler {
+		var o GELFOptions
+		if opts != nil {
+			o = *opts
+		}
+
+		if hOpts != nil {
+			o.HandlerOptions = *hOpts
+		}
+
+		if conn != nil {
+			w = conn
+		} else {
+			w = newlineWriter{w}
+		}
+
+		return NewGELFHandler(w, &o)
+	}
+}
+
+func (o *GELFOptions) encode(buf *bytes.Buffer, record slog.Record, obj *attrObject) {
+	msg := record.Message
+	if v, ok := obj.remove(slog.MessageKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			msg = v.String()
+		}
+	}
+
+	obj.remove(slog.LevelKey)
+
+	buf.WriteString(`{"version":"1.1","host":`)
+	appendJSONString(buf, o.Host)
+
+	buf.WriteString(`,"short_message":`)
+
+	short, _, _ := strings.Cut(msg, "\n")
+	if strings.TrimSpace(short) == "" {
+		// short_message is required, and Graylog rejects empty ones
+		short = "-"
+	}
+
+	appendJSONString(buf, short)
+
+	if msg != "" && msg != short {
+		buf.WriteString(`,"full_message":`)
+		appendJSONString(buf, msg)
+	}
+
+	if v, ok := obj.remove(slog.TimeKey); ok {
+		if v, ok := v.(slog.Value); ok && v.Kind() == slog.KindTime {
+			// seconds, with millisecond precision
+			buf.WriteString(`,"timestamp":`)
+			buf.WriteString(strconv.FormatFloat(float64(v.Time().UnixMilli())/1000, 'f', -1, 64))
+		}
+	}
+
+	buf.WriteString(`,"level":`)
+	buf.WriteString(strconv.Itoa(SeverityForLevel(record.Level)))
+
+	appendGELFFields(buf, "", obj)
+
+	buf.WriteByte('}')
+}
+
+func appendGELFFields(buf *bytes.Buffer, prefix string, obj *attrObject) {
+	for _, k := range obj.keys {
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			appendGELFFields(buf, prefix+k+"_", v)
+		case slog.Value:
+			buf.WriteByte(',')
+			appendJSONString(buf, gelfFieldName(prefix+k))
+			buf.WriteByte(':')
+
+			switch v.Kind() {
+			case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+				appendJSONValue(buf, v)
+			default:
+				appendJSONString(buf, syslogValueString(v))
+			}
+		}
+	}
+}
+
+// gelfFieldName converts an attr key into the name of an additional field.
+// Field names may only contain word characters, ".", and "-".
+func gelfFieldName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+
+	if s == "id" {
+		return "__id"
+	}
+
+	return "_" + s
+}
+
+// GELFConn is an io.WriteCloser which sends each Write to a GELF server as a single
+// message.  It connects lazily, on the first Write, and if a Write fails, it reconnects
+// and retries once.
+//
+// On "udp" networks, messages are optionally compressed, then split into chunks if
+// they are larger than ChunkSize.  On "tcp" networks, messages are terminated by a
+// null byte, and aren't compressed.
+type GELFConn struct {
+	// Compression applies to UDP messages.  Defaults to GELFCompressNone.
+	Compression GELFCompression
+
+	// ChunkSize is the maximum size of UDP datagrams.  Defaults to DefaultGELFChunkSize.
+	ChunkSize int
+
+	// DialTimeout is the timeout for establishing connections.  Defaults to 5 seconds.
+	DialTimeout time.Duration
+
+	// WriteTimeout, if set, is the deadline for each message write.
+	WriteTimeout time.Duration
+
+	rc *reconnectingConn
+}
+
+// NewGELFConn returns a GELFConn for the server at the network address.  It doesn't
+// connect until the first Write.
+func NewGELFConn(network, addr string) *GELFConn {
+	return &GELFConn{
+		ChunkSize:   DefaultGELFChunkSize,
+		DialTimeout: 5 * time.Second,
+		rc:          newReconnectingConn(network, addr, "gelf at "+network+"://"+addr, ErrGELFConnClosed),
+	}
+}
+
+// Write sends p as a single GELF message.
+func (c *GELFConn) Write(p []byte) (int, error) {
+	msgs, err := c.frame(p)
+	if err != nil {
+		return 0, err
+	}
+
+	err = c.rc.write(c.DialTimeout, c.WriteTimeout, func(conn net.Conn) error {
+		for _, msg := range msgs {
+			_, err := conn.Write(msg)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// frame converts a message into the packets to write to the connection.
+func (c *GELFConn) frame(p []byte) ([][]byte, error) {
+	if !c.isUDP() {
+		return [][]byte{append(p[:len(p):len(p)], 0)}, nil
+	}
+
+	p, err := c.compress(p)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultGELFChunkSize
+	}
+
+	if len(p) <= chunkSize {
+		return [][]byte{p}, nil
+	}
+
+	dataSize := chunkSize - gelfChunkHeaderLen
+	if dataSize <= 0 {
+		return nil, fmt.Errorf("%w: chunk size %d is too small", ErrGELFMessageTooLarge, chunkSize)
+	}
+
+	count := (len(p) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("%w: %d bytes needs %d chunks, maximum is %d", ErrGELFMessageTooLarge, len(p), count, gelfMaxChunks)
+	}
+
+	var id [8]byte
+
+	_, _ = rand.Read(id[:])
+
+	chunks := make([][]byte, 0, count)
+
+	for i := range count {
+		data := p[i*dataSize : min((i+1)*dataSize, len(p))]
+
+		chunk := make([]byte, 0, gelfChunkHeaderLen+len(data))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data...)
+
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
+
+func (c *GELFConn) compress(p []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+
+	switch c.Compression {
+	case GELFCompressGzip:
+		w = gzip.NewWriter(&buf)
+	case GELFCompressZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return p, nil
+	}
+
+	_, err := w.Write(p)
+	if err == nil {
+		err = w.Close()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("compressing gelf message: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *GELFConn) isUDP() bool {
+	network := c.rc.network
+
+	return network == "udp" || network == "udp4" || network == "udp6"
+}
+
+// Close closes the connection.  Subsequent writes will fail.
+func (c *GELFConn) Close() error {
+	return c.rc.close()
+}
@@ -0,0 +1,282 @@
+package flume
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGELFHandler(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 20, 41, 28, 515*1e6, time.UTC)
+
+	tests := []struct {
+		name    string
+		level   slog.Level
+		msg     string
+		handler func(h slog.Handler) slog.Handler
+		attrs   []any
+		want    string
+	}{
+		{
+			name: "defaults",
+			want: `{"version":"1.1","host":"myhost","short_message":"hi","timestamp":1704141688.515,"level":6}`,
+		},
+		{
+			name:  "attrs",
+			level: slog.LevelWarn,
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")})
+			},
+			attrs: []any{"color", "red", "size", 5, "ok", true, "elapsed", time.Second, slog.Group("req", "method", "GET", slog.Group("headers", "accept", "*/*"))},
+			want:  `{"version":"1.1","host":"myhost","short_message":"hi","timestamp":1704141688.515,"level":4,"_logger":"http","_color":"red","_size":5,"_ok":"true","_elapsed":"1s","_req_method":"GET","_req_headers_accept":"*/*"}`,
+		},
+		{
+			name: "with group",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("req").WithAttrs([]slog.Attr{slog.String("method", "GET")})
+			},
+			attrs: []any{"path", "/"},
+			want:  `{"version":"1.1","host":"myhost","short_message":"hi","timestamp":1704141688.515,"level":6,"_req_method":"GET","_req_path":"/"}`,
+		},
+		{
+			name:  "field names",
+			attrs: []any{"id", 1, "user id", 2, "a.b-c", 3},
+			want:  `{"version":"1.1","host":"myhost","short_message":"hi","timestamp":1704141688.515,"level":6,"__id":1,"_user_id":2,"_a.b-c":3}`,
+		},
+		{
+			name:  "multi-line message",
+			level: slog.LevelError,
+			msg:   "boom\nstack",
+			want:  `{"version":"1.1","host":"myhost","short_message":"boom","full_message":"boom\nstack","timestamp":1704141688.515,"level":3}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+
+			h := NewGELFHandler(buf, &GELFOptions{Host: "myhost"})
+			if tt.handler != nil {
+				h = tt.handler(h)
+			}
+
+			msg := tt.msg
+			if msg == "" {
+				msg = "hi"
+			}
+
+			rec := slog.NewRecord(fixedTime, tt.level, msg, 0)
+			rec.Add(tt.attrs...)
+
+			require.NoError(t, h.Handle(context.Background(), rec))
+			assert.Equal(t, tt.want, buf.String())
+			assert.True(t, json.Valid(buf.Bytes()))
+		})
+	}
+}
+
+func TestGELFHandler_emptyMessage(t *testing.T) {
+	tests := map[string]string{
+		"":        `{"version":"1.1","host":"myhost","short_message":"-","level":6}`,
+		" ":       `{"version":"1.1","host":"myhost","short_message":"-","full_message":" ","level":6}`,
+		"\nstack": `{"version":"1.1","host":"myhost","short_message":"-","full_message":"\nstack","level":6}`,
+	}
+
+	for msg, want := range tests {
+		buf := bytes.NewBuffer(nil)
+
+		require.NoError(t, NewGELFHandler(buf, &GELFOptions{Host: "myhost"}).Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)))
+		assert.Equal(t, want, buf.String(), "msg %q", msg)
+	}
+}
+
+func TestNewGELFHandlerFn_writer(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{HandlerFn: LookupHandlerFn(GELFHandler)}).Named("http"))
+
+	l.Info("one")
+	l.Info("two")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"short_message":"one"`)
+	assert.Contains(t, lines[1], `"short_message":"two"`)
+	assert.Contains(t, lines[1], `"_logger":"http"`)
+}
+
+func TestNewGELFHandlerFn_udp(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       *GELFOptions
+		decompress func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			name: "uncompressed",
+		},
+		{
+			name: "gzip",
+			opts: &GELFOptions{Compression: GELFCompressGzip},
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		{
+			name: "zlib",
+			opts: &GELFOptions{Compression: GELFCompressZlib},
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return zlib.NewReader(r)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			defer pc.Close()
+
+			l := slog.New(NewHandler(nil, &HandlerOptions{
+				HandlerFn: NewGELFHandlerFn("udp", pc.LocalAddr().String(), tt.opts),
+			}))
+			l.Warn("hi", "color", "red")
+
+			msg := readPacket(t, pc)
+
+			if tt.decompress != nil {
+				r, err := tt.decompress(bytes.NewReader(msg))
+				require.NoError(t, err)
+
+				msg, err = io.ReadAll(r)
+				require.NoError(t, err)
+			}
+
+			assert.Contains(t, string(msg), `"short_message":"hi"`)
+			assert.Contains(t, string(msg), `"level":4,"_color":"red"}`)
+		})
+	}
+}
+
+func TestGELFConn_chunking(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer pc.Close()
+
+	conn := NewGELFConn("udp", pc.LocalAddr().String())
+	conn.ChunkSize = 32
+
+	defer conn.Close()
+
+	msg := []byte(strings.Repeat("0123456789", 10))
+
+	_, err = conn.Write(msg)
+	require.NoError(t, err)
+
+	// 100 bytes, in chunks of 20 bytes of data
+	var chunks [][]byte
+	for range 5 {
+		chunks = append(chunks, readPacket(t, pc))
+	}
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i][10] < chunks[j][10] })
+
+	var reassembled []byte
+
+	for i, chunk := range chunks {
+		require.LessOrEqual(t, len(chunk), 32)
+		assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2], "magic bytes")
+		assert.Equal(t, chunks[0][2:10], chunk[2:10], "message id")
+		assert.Equal(t, []byte{byte(i), 5}, chunk[10:12], "sequence")
+
+		reassembled = append(reassembled, chunk[12:]...)
+	}
+
+	assert.Equal(t, msg, reassembled)
+
+	conn.ChunkSize = 13
+
+	_, err = conn.Write(make([]byte, 129))
+	require.ErrorIs(t, err, ErrGELFMessageTooLarge)
+}
+
+func TestGELFConn_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer c.Close()
+
+				r := bufio.NewReader(c)
+
+				for {
+					msg, err := r.ReadString(0)
+					if err != nil {
+						return
+					}
+
+					msgs <- strings.TrimSuffix(msg, "\x00")
+				}
+			}()
+		}
+	}()
+
+	conn := NewGELFConn("tcp", ln.Addr().String())
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"short_message":"first"}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"short_message":"first"}`, receive(t, msgs))
+
+	// break the connection.  The next write should fail, then reconnect and succeed.
+	conn.rc.mu.Lock()
+	_ = conn.rc.conn.Close()
+	conn.rc.mu.Unlock()
+
+	_, err = conn.Write([]byte(`{"short_message":"second"}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"short_message":"second"}`, receive(t, msgs))
+
+	require.NoError(t, conn.Close())
+
+	_, err = conn.Write([]byte("third"))
+	require.ErrorIs(t, err, ErrGELFConnClosed)
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) []byte {
+	t.Helper()
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	b := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	return b[:n]
+}
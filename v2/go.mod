@@ -1,12 +1,15 @@
 module github.com/ThalesGroup/flume/v2
 
-go 1.24
+go 1.24.0
 
 toolchain go1.24.10
 
 require (
 	github.com/ansel1/console-slog v0.6.0
+	github.com/ansel1/merry/v2 v2.1.1
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/term v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -14,6 +17,6 @@ require (
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,6 +2,8 @@ package flume
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -16,6 +18,11 @@ const (
 	LoggerKey = "logger"
 )
 
+// ErrDropped is wrapped by errors which handlers return when they intentionally discard
+// a record, for example because a queue is full.  Metrics counts these as dropped records,
+// rather than write errors.
+var ErrDropped = errors.New("record dropped")
+
 func (o *HandlerOptions) handler(name string, w io.Writer) slog.Handler {
 	if w == nil {
 		w = os.Stdout
@@ -26,10 +33,16 @@ func (o *HandlerOptions) handler(name string, w io.Writer) slog.Handler {
 		return TextHandlerFn()(name, w, &slog.HandlerOptions{})
 	}
 
+	replaceAttrs := o.ReplaceAttrs
+	if o.TrimSource != nil {
+		// trim first, so the other ReplaceAttr functions see the trimmed source
+		replaceAttrs = append([]func([]string, slog.Attr) slog.Attr{TrimSource(*o.TrimSource)}, replaceAttrs...)
+	}
+
 	opts := &slog.HandlerOptions{
 		Level:       o.Level,
 		AddSource:   o.AddSource,
-		ReplaceAttr: ChainReplaceAttrs(o.ReplaceAttrs...),
+		ReplaceAttr: ChainReplaceAttrs(replaceAttrs...),
 	}
 
 	if name != "" {
@@ -50,10 +63,26 @@ func (o *HandlerOptions) handler(name string, w io.Writer) slog.Handler {
 		sink = noop
 	}
 
+	if o.AddStacktrace != nil {
+		sink = StacktraceMiddleware(o.AddStacktrace, o.StacktraceSkip).Apply(sink)
+	}
+
+	if o.Limits != nil {
+		sink = LimitsMiddleware(*o.Limits).Apply(sink)
+	}
+
 	for i := len(o.Middleware) - 1; i >= 0; i-- {
 		sink = o.Middleware[i].Apply(sink)
 	}
 
+	if len(o.Attrs) > 0 {
+		sink = sink.WithAttrs(o.Attrs)
+	}
+
+	if o.ErrorPolicy != nil {
+		sink = &errorPolicyHandler{policy: o.ErrorPolicy, next: sink}
+	}
+
 	return sink
 }
 
@@ -153,6 +182,76 @@ func (h *Handler) SetOut(w io.Writer) {
 	h.reset()
 }
 
+// setOutAndHandlerOptions is like SetOut followed by SetHandlerOptions, but rebuilds
+// the sinks once, so records are never written to w with the old options.
+func (h *Handler) setOutAndHandlerOptions(w io.Writer, opts *HandlerOptions) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.w = w
+	h.opts = opts.Clone()
+	h.reset()
+}
+
+// Flusher is implemented by handlers, middleware, and writers which buffer
+// records, and need to be flushed before the program exits.
+type Flusher interface {
+	// Flush writes any buffered records, and waits for the writes to complete,
+	// or for ctx to be done.
+	Flush(ctx context.Context) error
+}
+
+// Flush flushes all the sink handlers which implement Flusher, including
+// sinks wrapped by middleware, then flushes the output writer, if it has
+// a Flush method.  Fatal and Panic call Flush before exiting.
+func (h *Handler) Flush(ctx context.Context) error {
+	h.mutex.Lock()
+
+	sinks := make([]slog.Handler, 0, len(h.delegates))
+	for _, ptr := range h.delegates {
+		if sink := ptr.Load(); sink != nil {
+			sinks = append(sinks, *sink)
+		}
+	}
+
+	w := h.w
+	h.mutex.Unlock()
+
+	var errs []error
+
+	for _, sink := range sinks {
+		errs = append(errs, flushHandler(ctx, sink))
+	}
+
+	errs = append(errs, flushWriter(ctx, w))
+
+	return errors.Join(errs...)
+}
+
+func flushHandler(ctx context.Context, h slog.Handler) error {
+	if f, ok := h.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+
+	return nil
+}
+
+// flushWriter flushes writers which implement Flusher, or have a
+// Flush() error method, like bufio.Writer.
+func flushWriter(ctx context.Context, w io.Writer) error {
+	switch w := w.(type) {
+	case Flusher:
+		return w.Flush(ctx)
+	case interface{ Flush() error }:
+		err := w.Flush()
+		if err != nil {
+			return fmt.Errorf("flushing writer: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (h *Handler) reset() {
 	for name, ptr := range h.delegates {
 		sink := h.opts.handler(name, h.w)
@@ -241,6 +340,11 @@ func (s *innerHandler) Handle(ctx context.Context, record slog.Record) error {
 	return s.delegate().Handle(ctx, record)
 }
 
+// Flush flushes the root Handler.
+func (s *innerHandler) Flush(ctx context.Context) error {
+	return s.root.Flush(ctx)
+}
+
 func (s *innerHandler) delegate() slog.Handler {
 	base := s.basePtr.Load()
 
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log/slog"
 	"maps"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
@@ -14,9 +15,12 @@ import (
 
 // Define static error variables
 var (
-	ErrInvalidLevels       = errors.New("invalid levels value")
-	ErrInvalidLevel        = errors.New("invalid log level")
-	ErrUnregisteredHandler = errors.New("unregistered handler")
+	ErrInvalidLevels        = errors.New("invalid levels value")
+	ErrInvalidLevel         = errors.New("invalid log level")
+	ErrUnregisteredHandler  = errors.New("unregistered handler")
+	ErrInvalidPlaceholder   = errors.New("invalid placeholder")
+	ErrInvalidErrorFallback = errors.New("invalid error fallback")
+	ErrUnknownProperty      = errors.New("unknown property")
 )
 
 // HandlerFn is a constructor for slog handlers.  The function should return a slog.Handler
@@ -41,11 +45,26 @@ type HandlerOptions struct {
 	HandlerFn HandlerFn
 	// middleware applied to all sinks
 	Middleware []Middleware
+	// add a stack trace to records at or above this level.  nil disables stack traces.
+	// See StacktraceMiddleware.
+	AddStacktrace slog.Leveler
+	// number of frames to skip at the top of stack traces, for logging wrappers
+	StacktraceSkip int
+	// if set, shortens source locations.  See TrimSource.
+	TrimSource *SourceOptions
+	// attrs added to every record, like the service name and version
+	Attrs []slog.Attr
+	// if set, reports errors returned by sinks.  Shared by clones of the options.
+	ErrorPolicy *ErrorPolicy
+	// if set, limits the size of records.  See LimitsMiddleware.
+	Limits *Limits
 }
 
+// DevDefaults returns options suited to local development: human-friendly
+// term output, colored when writing to a terminal, with source locations.
 func DevDefaults() *HandlerOptions {
 	return &HandlerOptions{
-		HandlerFn: TermColorHandlerFn(),
+		HandlerFn: TermAutoHandlerFn(),
 		AddSource: true,
 	}
 }
@@ -62,21 +81,68 @@ func (o *HandlerOptions) Clone() *HandlerOptions {
 		HandlerFn:    o.HandlerFn,
 		ReplaceAttrs: slices.Clone(o.ReplaceAttrs),
 		Middleware:   slices.Clone(o.Middleware),
+
+		AddStacktrace:  o.AddStacktrace,
+		StacktraceSkip: o.StacktraceSkip,
+		Attrs:          slices.Clone(o.Attrs),
+		ErrorPolicy:    o.ErrorPolicy,
+	}
+
+	if o.Limits != nil {
+		limits := *o.Limits
+		ret.Limits = &limits
+	}
+
+	if o.TrimSource != nil {
+		trimSource := *o.TrimSource
+		ret.TrimSource = &trimSource
 	}
 
 	return ret
 }
 
+// jsonHandlerOptions is the json config.  The doc and schema tags are used to generate
+// the JSON Schema, and to validate the config in UnmarshalJSONStrict.  The schema tag
+// names a type in the schema's $defs, and the enum tag lists the allowed values.
+type jsonHandlerOptions struct {
+	Development bool   `json:"development" doc:"start from DevDefaults(): term output, with source locations"`
+	Handler     string `json:"handler"     doc:"the name of a registered handler, see LookupHandlerFn"          schema:"handler"`
+	Level       any    `json:"level"       doc:"the default level for all loggers"                              schema:"level"`
+	Levels      any    `json:"levels"      doc:"per-logger levels, as a levels string, or an object of levels"  schema:"levels"`
+	AddSource   *bool  `json:"addSource"   doc:"add source locations to records"`
+	AddCaller   *bool  `json:"addCaller"   doc:"v1 alias for addSource; if both are set, addSource wins"`
+	Encoding    string `json:"encoding"    doc:"v1 alias for handler; if both are set, handler wins"            schema:"handler"`
+
+	AddStacktrace  any            `json:"addStacktrace"  doc:"add stack traces to records at or above this level"                schema:"level"`
+	StacktraceSkip *int           `json:"stacktraceSkip" doc:"frames to skip at the top of stack traces"`
+	TrimSource     *SourceOptions `json:"trimSource"     doc:"shortens source locations, see TrimSource"`
+	Attrs          map[string]any `json:"attrs"          doc:"attrs added to every record; strings may contain placeholders"  schema:"attrs"`
+	Fields         map[string]any `json:"fields"         doc:"alias for attrs; if both are set, attrs wins"                      schema:"attrs"`
+	ErrorFallback  string         `json:"errorFallback"  doc:"reports sink errors to stderr or stdout, see ErrorPolicy"          enum:"stderr,stdout"`
+	Limits         *Limits        `json:"limits"         doc:"limits the size of records, see LimitsMiddleware"`
+}
+
+// UnmarshalJSON configures the options from JSON, like the value of the FLUME environment
+// variable.  See UnmarshalEnv for the supported properties.
+//
+// The "attrs" property, or its alias "fields", is an object of attrs added to every
+// record.  Nested objects are added as groups.  String values may contain placeholders,
+// which are resolved when the JSON is unmarshaled:
+//
+//	${hostname}    the hostname reported by the kernel
+//	${pid}         the process ID
+//	${version}     the version of the main module, from the binary's build info
+//	${revision}    the VCS revision the binary was built from, from the build info
+//	${env:NAME}    the value of the environment variable NAME
+//
+// For example:
+//
+//	{"attrs":{"service":"billing","version":"${version}","host":"${hostname}","region":"${env:REGION}"}}
+//
+// Unknown properties are ignored, and only the first invalid value is reported.  See
+// UnmarshalJSONStrict for validating configs.
 func (o *HandlerOptions) UnmarshalJSON(bytes []byte) error {
-	s := struct {
-		Development bool   `json:"development"`
-		Handler     string `json:"handler"`
-		Level       any    `json:"level"`
-		Levels      any    `json:"levels"`
-		AddSource   *bool  `json:"addSource"`
-		AddCaller   *bool  `json:"addCaller"`
-		Encoding    string `json:"encoding"`
-	}{}
+	var s jsonHandlerOptions
 
 	err := json.Unmarshal(bytes, &s)
 	if err != nil {
@@ -149,6 +215,40 @@ func (o *HandlerOptions) UnmarshalJSON(bytes []byte) error {
 		opts.AddSource = *s.AddSource
 	}
 
+	if s.AddStacktrace != nil {
+		level, err := parseLevel(s.AddStacktrace)
+		if err != nil {
+			return err
+		}
+
+		opts.AddStacktrace = level
+		if level == LevelOff {
+			opts.AddStacktrace = nil
+		}
+	}
+
+	if s.StacktraceSkip != nil {
+		opts.StacktraceSkip = *s.StacktraceSkip
+	}
+
+	if s.TrimSource != nil {
+		opts.TrimSource = s.TrimSource
+	}
+
+	// allow "fields" as an alias for "attrs"
+	if s.Attrs == nil {
+		s.Attrs = s.Fields
+	}
+
+	if s.Attrs != nil {
+		attrs, err := parseStaticAttrs(s.Attrs)
+		if err != nil {
+			return err
+		}
+
+		opts.Attrs = attrs
+	}
+
 	// for backward compat with v1, allow "encoding" as
 	// an alias for "handler"
 	if s.Handler == "" {
@@ -163,6 +263,20 @@ func (o *HandlerOptions) UnmarshalJSON(bytes []byte) error {
 		}
 	}
 
+	if s.Limits != nil {
+		opts.Limits = s.Limits
+	}
+
+	switch s.ErrorFallback {
+	case "":
+	case "stderr":
+		opts.ErrorPolicy = &ErrorPolicy{Fallback: os.Stderr}
+	case "stdout":
+		opts.ErrorPolicy = &ErrorPolicy{Fallback: os.Stdout}
+	default:
+		return fmt.Errorf("%w: '%v': must be stderr or stdout", ErrInvalidErrorFallback, s.ErrorFallback)
+	}
+
 	if s.Handler != "" {
 		fn := LookupHandlerFn(s.Handler)
 		if fn == nil {
@@ -177,20 +291,6 @@ func (o *HandlerOptions) UnmarshalJSON(bytes []byte) error {
 	return nil
 }
 
-const (
-	dbgAbbrev = "DBG"
-	infAbbrev = "INF"
-	wrnAbbrev = "WRN"
-	errAbbrev = "ERR"
-)
-
-var levelAbbreviations = map[slog.Level]string{
-	slog.LevelDebug: "DBG",
-	slog.LevelInfo:  "INF",
-	slog.LevelWarn:  "WRN",
-	slog.LevelError: "ERR",
-}
-
 func parseLevel(v any) (slog.Level, error) {
 	var s string
 
@@ -232,16 +332,15 @@ func parseLevel(v any) (slog.Level, error) {
 		return LevelOff, nil
 	}
 
-	// convert abbreviations to full length values
-	// also support the level offset convention slog supports, i.e. WRN+3 = WARNING+3 = 4+3 = 7
-	if len(s) == 3 || strings.IndexAny(s, "+-") == 3 {
-		for level, abbr := range levelAbbreviations {
-			if strings.HasPrefix(s, abbr) {
-				s = level.String() + s[3:]
-			}
-		}
+	// registered level names and abbreviations, including slog's levels.
+	// also support the level offset convention slog supports, i.e. WRN+3 = WARN+3 = 4+3 = 7
+	if l, ok := lookupLevel(s); ok {
+		return l, nil
 	}
 
+	// fall back on slog's parser, which produces more detailed errors
+	s = expandLevelAbbreviation(s)
+
 	var l slog.Level
 
 	err := l.UnmarshalText([]byte(s))
@@ -270,19 +369,7 @@ func (l *Levels) MarshalText() ([]byte, error) {
 		return []byte{}, nil
 	}
 
-	directives := make([]string, 0, len(*l))
-	for name, level := range *l {
-		switch level {
-		case LevelOff:
-			directives = append(directives, "-"+name)
-		case LevelAll:
-			directives = append(directives, name)
-		default:
-			directives = append(directives, name+"="+level.Level().String())
-		}
-	}
-
-	return []byte(strings.Join(directives, ",")), nil
+	return []byte(levelsString(*l)), nil
 }
 
 func parseLevels(s string) (map[string]slog.Leveler, error) {
@@ -42,6 +42,9 @@ func TestParseLevel(t *testing.T) {
 		slog.LevelError:    {"ERR", "ERROR", "erRor", "eRr", float64(8), "8", int(8)},
 		slog.LevelWarn + 3: {"WRN+3", "WARN+3", int(slog.LevelWarn + 3)},
 		slog.LevelWarn - 2: {"WRN-2", "WARN-2", int(slog.LevelWarn - 2)},
+		LevelTrace:         {"TRC", "TRACE", "trace", int(LevelTrace)},
+		LevelNotice + 1:    {"NTC+1", "NOTICE+1"},
+		LevelFatal:         {"FTL", "FATAL", "Fatal"},
 		math.MaxInt:        {"OFF", int(math.MaxInt), false},
 		math.MinInt:        {"ALL", int(math.MinInt), true},
 	}
@@ -115,8 +118,10 @@ func TestLevels_MarshalText(t *testing.T) {
 				"off":    LevelOff,
 				"all":    LevelAll,
 				"offset": slog.LevelDebug + 2,
+				"fatal":  LevelFatal,
+				"notice": LevelNotice + 1,
 			},
-			expected: "info=INFO,warn=WARN,error=ERROR,debug=DEBUG,-off,all,offset=DEBUG+2",
+			expected: "info=INFO,warn=WARN,error=ERROR,debug=DEBUG,-off,all,offset=DEBUG+2,fatal=FATAL,notice=INFO+3",
 		},
 	}
 
@@ -213,7 +218,10 @@ func TestLevels_UnmarshalText(t *testing.T) {
 }
 
 func TestHandlerOptions_UnmarshalJSON(t *testing.T) {
-	theme := console.NewDefaultTheme()
+	// term-auto handler is sensitive to these
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+
 	tests := []struct {
 		name      string
 		confJSON  string
@@ -241,8 +249,8 @@ func TestHandlerOptions_UnmarshalJSON(t *testing.T) {
 			name:     "dev defaults",
 			confJSON: `{"development":true}`,
 			expected: *DevDefaults(),
-			want: styled("|", theme.Header) + styled("INF", theme.LevelInfo) + styled("|", theme.Header) + " " +
-				styled("hi", theme.Message) + "\n",
+			// dev defaults only use color when writing to a terminal
+			want: "|INF| hi\n",
 		},
 		{
 			name:     "int level",
@@ -330,6 +338,41 @@ func TestHandlerOptions_UnmarshalJSON(t *testing.T) {
 				AddSource: false,
 			},
 		},
+		{
+			name:     "addStacktrace",
+			confJSON: `{"addStacktrace":"ERR", "stacktraceSkip":2}`,
+			expected: HandlerOptions{
+				AddStacktrace:  slog.LevelError,
+				StacktraceSkip: 2,
+			},
+		},
+		{
+			name:     "trimSource",
+			confJSON: `{"trimSource":{"pathSegments":2}}`,
+			expected: HandlerOptions{
+				TrimSource: &SourceOptions{PathSegments: 2},
+			},
+		},
+		{
+			name:     "attrs",
+			confJSON: `{"attrs":{"service":"billing"}, "handler":"text"}`,
+			expected: HandlerOptions{
+				HandlerFn: TextHandlerFn(),
+				Attrs:     []slog.Attr{slog.String("service", "billing")},
+			},
+			want: "level=INFO msg=hi service=billing\n",
+		},
+		{
+			name:     "addStacktrace off",
+			confJSON: `{"addStacktrace":false}`,
+			expected: HandlerOptions{},
+		},
+		{
+			name:      "invalid addStacktrace",
+			confJSON:  `{"addStacktrace":"INVALID"}`,
+			wantErr:   "invalid log level 'INVALID': slog: level string \"INVALID\": unknown name",
+			wantErrIs: ErrInvalidLevel,
+		},
 		{
 			name:     "text handler",
 			confJSON: `{"handler":"text"}`,
@@ -362,6 +405,15 @@ func TestHandlerOptions_UnmarshalJSON(t *testing.T) {
 			},
 			want: "\x1b[2;1m|\x1b[0m\x1b[36mINF\x1b[0m\x1b[2;1m|\x1b[0m \x1b[1mhi\x1b[0m\n",
 		},
+		{
+			name:     "term auto handler",
+			confJSON: `{"handler":"term-auto"}`,
+			expected: HandlerOptions{
+				HandlerFn: TermAutoHandlerFn(),
+			},
+			// output is a buffer, not a terminal, so no color
+			want: "|INF| hi\n",
+		},
 		{
 			name:     "noop handler",
 			confJSON: `{"handler":"noop"}`,
@@ -447,6 +499,14 @@ func assertHandlerOptionsEqual(t *testing.T, want, got HandlerOptions, sample st
 
 	assert.Equal(t, want.AddSource, got.AddSource)
 
+	assert.Equal(t, want.AddStacktrace, got.AddStacktrace)
+
+	assert.Equal(t, want.StacktraceSkip, got.StacktraceSkip)
+
+	assert.Equal(t, want.TrimSource, got.TrimSource)
+
+	assert.Equal(t, want.Attrs, got.Attrs)
+
 	if want.ReplaceAttrs != nil {
 		assert.NotNil(t, got.ReplaceAttrs)
 		assert.Len(t, got.ReplaceAttrs, len(want.ReplaceAttrs))
@@ -341,6 +341,10 @@ func TestHandlerOptions_Clone(t *testing.T) {
 				HandlerFn: func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
 					return slog.NewTextHandler(w, opts)
 				},
+				AddStacktrace:  slog.LevelError,
+				StacktraceSkip: 1,
+				TrimSource:     &SourceOptions{ModuleRelative: true},
+				Attrs:          []slog.Attr{slog.String("service", "billing")},
 			},
 		},
 		{
@@ -370,6 +374,8 @@ func TestHandlerOptions_Clone(t *testing.T) {
 				return nil
 			}))
 			tC.opts.HandlerFn = nil
+			tC.opts.TrimSource.ModuleRelative = false
+			tC.opts.Attrs[0] = slog.String("service", "payroll")
 
 			assert.Equal(t, slog.LevelInfo, clone.Level)
 			assert.Equal(t, Levels{
@@ -379,6 +385,8 @@ func TestHandlerOptions_Clone(t *testing.T) {
 			assert.Len(t, clone.ReplaceAttrs, 1)
 			assert.Len(t, clone.Middleware, 1)
 			assert.NotNil(t, clone.HandlerFn)
+			assert.Equal(t, &SourceOptions{ModuleRelative: true}, clone.TrimSource)
+			assert.Equal(t, []slog.Attr{slog.String("service", "billing")}, clone.Attrs)
 		})
 	}
 }
@@ -0,0 +1,24 @@
+// Command genschema writes the JSON Schema of flume's json config to a file.
+//
+//	go run ./internal/genschema flume.schema.json
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ThalesGroup/flume/v2"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: genschema <file>")
+		os.Exit(2)
+	}
+
+	err := os.WriteFile(os.Args[1], flume.JSONSchema(), 0o644) //nolint:gosec // the schema is public
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
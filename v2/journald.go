@@ -0,0 +1,200 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultJournaldSocket is the path of systemd-journald's native protocol socket.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldOptions configures NewJournaldHandler.
+type JournaldOptions struct {
+	// Level, AddSource, and ReplaceAttr work the same as
+	// in the slog handlers.
+	slog.HandlerOptions
+
+	// SyslogIdentifier is the SYSLOG_IDENTIFIER of records which don't have
+	// a LoggerKey attr.  Defaults to the base name of the program.
+	SyslogIdentifier string
+}
+
+// NewJournaldHandler returns a slog.Handler which formats records as systemd-journald
+// native protocol messages, and writes them to w, one message per call to Write.
+// To send messages to journald, use NewJournaldHandlerFn.  opts may be nil.
+//
+// Each record is sent as a set of journal fields:
+//
+//   - MESSAGE: the record's message
+//   - PRIORITY: the record's level, mapped to a syslog severity with SeverityForLevel
+//   - SYSLOG_IDENTIFIER: the value of the LoggerKey attr
+//   - CODE_FILE, CODE_LINE, CODE_FUNC: the source, if AddSource is enabled
+//
+// All other attrs are sent as fields named after the attr key, converted to
+// a valid journal field name: upper-cased, with characters other than A-Z, 0-9, and
+// "_" replaced with "_", and leading underscores removed.  Attrs in groups are
+// flattened, and the group names are prepended to the field name, joined by "_".
+// So the attr "method" in the group "req" becomes REQ_METHOD.
+//
+// The record's time isn't sent.  Journald timestamps each entry as it's received.
+func NewJournaldHandler(w io.Writer, opts *JournaldOptions) slog.Handler {
+	var o JournaldOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.SyslogIdentifier == "" {
+		o.SyslogIdentifier = filepath.Base(os.Args[0])
+	}
+
+	return newObjectHandler(w, &o.HandlerOptions, func(buf *bytes.Buffer, record slog.Record, obj *attrObject) error {
+		o.encode(buf, record, obj)
+		return nil
+	})
+}
+
+// NewJournaldHandlerFn returns a HandlerFn which sends records to the journald socket at
+// socketPath, which defaults to DefaultJournaldSocket, using a single, shared connection.
+// The connection is established lazily, and re-established if a write fails.
+//
+// Messages must fit in a single datagram.  Journald's native protocol supports larger
+// messages by passing a memfd, but that isn't supported.
+//
+// The Level, AddSource, and ReplaceAttr options passed to the HandlerFn override
+// those in opts.
+func NewJournaldHandlerFn(socketPath string, opts *JournaldOptions) HandlerFn {
+	if socketPath == "" {
+		socketPath = DefaultJournaldSocket
+	}
+
+	conn := &journalConn{rc: newReconnectingConn("unixgram", socketPath, "journald socket "+socketPath, nil)}
+
+	return func(_ string, _ io.Writer, hOpts *slog.HandlerOptions) slog.Handler {
+		var o JournaldOptions
+		if opts != nil {
+			o = *opts
+		}
+
+		if hOpts != nil {
+			o.HandlerOptions = *hOpts
+		}
+
+		return NewJournaldHandler(conn, &o)
+	}
+}
+
+func (o *JournaldOptions) encode(buf *bytes.Buffer, record slog.Record, obj *attrObject) {
+	msg := record.Message
+	if v, ok := obj.remove(slog.MessageKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			msg = v.String()
+		}
+	}
+
+	obj.remove(slog.TimeKey)
+	obj.remove(slog.LevelKey)
+
+	identifier := o.SyslogIdentifier
+	if v, ok := obj.remove(LoggerKey); ok {
+		if v, ok := v.(slog.Value); ok && v.String() != "" {
+			identifier = v.String()
+		}
+	}
+
+	appendJournalField(buf, "MESSAGE", msg)
+	appendJournalField(buf, "PRIORITY", strconv.Itoa(SeverityForLevel(record.Level)))
+	appendJournalField(buf, "SYSLOG_IDENTIFIER", identifier)
+
+	if v, ok := obj.remove(slog.SourceKey); ok {
+		if v, ok := v.(slog.Value); ok && v.Kind() == slog.KindAny {
+			if src, ok := v.Any().(*slog.Source); ok {
+				appendJournalField(buf, "CODE_FILE", src.File)
+				appendJournalField(buf, "CODE_LINE", strconv.Itoa(src.Line))
+				appendJournalField(buf, "CODE_FUNC", src.Function)
+			}
+		}
+	}
+
+	appendJournalFields(buf, "", obj)
+}
+
+func appendJournalFields(buf *bytes.Buffer, prefix string, obj *attrObject) {
+	for _, k := range obj.keys {
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			appendJournalFields(buf, prefix+k+"_", v)
+		case slog.Value:
+			appendJournalField(buf, journalFieldName(prefix+k), syslogValueString(v))
+		}
+	}
+}
+
+// appendJournalField appends a field in the native protocol's format.  Values
+// which contain newlines are encoded in the binary-safe form: the name, a newline,
+// the length of the value as a little-endian uint64, then the value.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+
+	if strings.Contains(value, "\n") {
+		buf.WriteByte('\n')
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	} else {
+		buf.WriteByte('=')
+	}
+
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts s into a valid journal field name.  Valid names contain only
+// upper-case letters, digits, and underscores, don't start with an underscore or digit,
+// and are at most 64 characters long.
+func journalFieldName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+
+	// leading underscores denote trusted fields, which clients can't set
+	name = strings.TrimLeft(name, "_")
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// journalConn sends each Write as a datagram to the journald socket.  It's never closed.
+type journalConn struct {
+	rc *reconnectingConn
+}
+
+func (c *journalConn) Write(p []byte) (int, error) {
+	err := c.rc.write(0, 0, func(conn net.Conn) error {
+		_, err := conn.Write(p)
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,143 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournaldHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *JournaldOptions
+		level   slog.Level
+		handler func(h slog.Handler) slog.Handler
+		attrs   []any
+		want    string
+	}{
+		{
+			name: "defaults",
+			want: "MESSAGE=hi\nPRIORITY=6\nSYSLOG_IDENTIFIER=myapp\n",
+		},
+		{
+			name:  "attrs",
+			level: slog.LevelWarn,
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")})
+			},
+			attrs: []any{"color", "red", "size", 5, slog.Group("req", "method", "GET", slog.Group("headers", "accept", "*/*"))},
+			want:  "MESSAGE=hi\nPRIORITY=4\nSYSLOG_IDENTIFIER=http\nCOLOR=red\nSIZE=5\nREQ_METHOD=GET\nREQ_HEADERS_ACCEPT=*/*\n",
+		},
+		{
+			name: "with group",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("req").WithAttrs([]slog.Attr{slog.String("method", "GET")})
+			},
+			attrs: []any{"path", "/"},
+			want:  "MESSAGE=hi\nPRIORITY=6\nSYSLOG_IDENTIFIER=myapp\nREQ_METHOD=GET\nREQ_PATH=/\n",
+		},
+		{
+			name:  "sanitized keys",
+			attrs: []any{"_private", 1, "user-id", 2, "2fa", true, "héllo", "x"},
+			want:  "MESSAGE=hi\nPRIORITY=6\nSYSLOG_IDENTIFIER=myapp\nPRIVATE=1\nUSER_ID=2\nX2FA=true\nH_LLO=x\n",
+		},
+		{
+			name:  "multi-line value",
+			level: slog.LevelError,
+			attrs: []any{"stack", "line1\nline2"},
+			want: "MESSAGE=hi\nPRIORITY=3\nSYSLOG_IDENTIFIER=myapp\nSTACK\n" +
+				string(binary.LittleEndian.AppendUint64(nil, 11)) + "line1\nline2\n",
+		},
+		{
+			name: "replace attr",
+			opts: &JournaldOptions{HandlerOptions: slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.MessageKey {
+						a.Value = slog.StringValue(strings.ToUpper(a.Value.String()))
+					}
+
+					return a
+				},
+			}},
+			want: "MESSAGE=HI\nPRIORITY=6\nSYSLOG_IDENTIFIER=myapp\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := JournaldOptions{}
+			if tt.opts != nil {
+				opts = *tt.opts
+			}
+
+			opts.SyslogIdentifier = "myapp"
+
+			buf := bytes.NewBuffer(nil)
+
+			h := NewJournaldHandler(buf, &opts)
+			if tt.handler != nil {
+				h = tt.handler(h)
+			}
+
+			rec := slog.NewRecord(time.Now(), tt.level, "hi", 0)
+			rec.Add(tt.attrs...)
+
+			require.NoError(t, h.Handle(context.Background(), rec))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestJournaldHandler_source(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewJournaldHandler(buf, &JournaldOptions{HandlerOptions: slog.HandlerOptions{AddSource: true}}))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	l.Info("hi")
+
+	assert.Contains(t, buf.String(), "\nCODE_FILE="+file+"\n")
+	assert.Contains(t, buf.String(), "\nCODE_LINE="+strconv.Itoa(line+2)+"\n")
+	assert.Contains(t, buf.String(), "\nCODE_FUNC=github.com/ThalesGroup/flume/v2.TestJournaldHandler_source\n")
+}
+
+func TestNewJournaldHandlerFn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+
+	pc, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+
+	defer pc.Close()
+
+	l := slog.New(NewHandler(nil, &HandlerOptions{
+		HandlerFn: NewJournaldHandlerFn(path, nil),
+	}).Named("http"))
+	l.Warn("hi", "color", "red")
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "MESSAGE=hi\nPRIORITY=4\nSYSLOG_IDENTIFIER=http\nCOLOR=red\n", string(b[:n]))
+}
+
+func TestNewJournaldHandlerFn_dialError(t *testing.T) {
+	h := NewJournaldHandlerFn(filepath.Join(t.TempDir(), "missing.sock"), nil)("", nil, nil)
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hi", 0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "writing to journald socket")
+}
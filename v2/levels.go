@@ -0,0 +1,230 @@
+package flume
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	dbgAbbrev = "DBG"
+	infAbbrev = "INF"
+	wrnAbbrev = "WRN"
+	errAbbrev = "ERR"
+)
+
+// ErrInvalidLevelName is returned by RegisterLevel if the name or abbreviation
+// can't be used.
+var ErrInvalidLevelName = errors.New("invalid level name")
+
+type levelName struct {
+	level  slog.Level
+	name   string
+	abbrev string
+}
+
+var (
+	levelRegistryMu sync.RWMutex
+	// registered levels, sorted by level
+	registeredLevels []levelName
+)
+
+//nolint:gochecknoinits
+func init() {
+	resetLevels()
+}
+
+func resetLevels() {
+	levelRegistryMu.Lock()
+	defer levelRegistryMu.Unlock()
+
+	registeredLevels = []levelName{
+		{level: LevelTrace, name: "TRACE", abbrev: "TRC"},
+		{level: slog.LevelDebug, name: "DEBUG", abbrev: dbgAbbrev},
+		{level: slog.LevelInfo, name: "INFO", abbrev: infAbbrev},
+		{level: LevelNotice, name: "NOTICE", abbrev: "NTC"},
+		{level: slog.LevelWarn, name: "WARN", abbrev: wrnAbbrev},
+		{level: slog.LevelError, name: "ERROR", abbrev: errAbbrev},
+		{level: LevelFatal, name: "FATAL", abbrev: "FTL"},
+	}
+}
+
+// RegisterLevel registers a name and abbreviation for a custom level.  Registered
+// names are accepted wherever levels are parsed, like the "level" and "levels"
+// options, and are used to render levels by Levels.MarshalText, AbbreviateLevel,
+// NameLevel, and the term handlers.  Names are case-insensitive, and are
+// rendered in upper case.  If abbrev is empty, the name is also used as the
+// abbreviation.
+//
+// TRACE, NOTICE, and FATAL are registered by default, in addition to slog's levels.
+// Registering a name for a level which already has one replaces it, but the names
+// of slog's built-in levels can't be changed.
+//
+// Only the registered level itself is rendered with its name.  Other levels are
+// rendered like slog does, as an offset from one of slog's levels, i.e. NOTICE+1 is
+// rendered as "INFO+3", but can be parsed from either.
+func RegisterLevel(level slog.Level, name, abbrev string) error {
+	name = strings.ToUpper(name)
+	abbrev = strings.ToUpper(abbrev)
+
+	if abbrev == "" {
+		abbrev = name
+	}
+
+	for _, s := range []string{name, abbrev} {
+		if !validLevelName(s) {
+			return fmt.Errorf("%w: %q must be letters, digits, and underscores, and must not start with a digit", ErrInvalidLevelName, s)
+		}
+	}
+
+	levelRegistryMu.Lock()
+	defer levelRegistryMu.Unlock()
+
+	for _, ln := range registeredLevels {
+		if ln.level == level {
+			if isSlogLevel(level) {
+				return fmt.Errorf("%w: can't rename built-in level %v", ErrInvalidLevelName, level)
+			}
+
+			continue
+		}
+
+		if name == ln.name || name == ln.abbrev || abbrev == ln.name || abbrev == ln.abbrev {
+			return fmt.Errorf("%w: %q is already registered to level %d", ErrInvalidLevelName, name, ln.level)
+		}
+	}
+
+	registeredLevels = slices.DeleteFunc(registeredLevels, func(ln levelName) bool { return ln.level == level })
+	registeredLevels = append(registeredLevels, levelName{level: level, name: name, abbrev: abbrev})
+	slices.SortFunc(registeredLevels, func(a, b levelName) int { return cmp.Compare(a.level, b.level) })
+
+	return nil
+}
+
+func validLevelName(s string) bool {
+	if s == "" || s == "ALL" || s == "OFF" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isSlogLevel(l slog.Level) bool {
+	return l == slog.LevelDebug || l == slog.LevelInfo || l == slog.LevelWarn || l == slog.LevelError
+}
+
+// formatLevel renders a level with its registered name or abbreviation.  Levels
+// which aren't registered are rendered like slog does, as an offset from the closest
+// of slog's levels below them, or from DEBUG if they are below it, so adding a name for
+// one level doesn't change how the levels around it are rendered.
+func formatLevel(l slog.Level, abbreviated bool) string {
+	ln, ok := registeredLevel(l)
+	if !ok {
+		ln, _ = registeredLevel(slogBaseLevel(l))
+	}
+
+	s := ln.name
+	if abbreviated {
+		s = ln.abbrev
+	}
+
+	if delta := int(l) - int(ln.level); delta != 0 {
+		s += fmt.Sprintf("%+d", delta)
+	}
+
+	return s
+}
+
+// registeredLevel returns the name registered for a level.
+func registeredLevel(l slog.Level) (levelName, bool) {
+	levelRegistryMu.RLock()
+	defer levelRegistryMu.RUnlock()
+
+	for _, ln := range registeredLevels {
+		if ln.level == l {
+			return ln, true
+		}
+	}
+
+	return levelName{}, false
+}
+
+// slogBaseLevel returns the slog level which slog.Level.String renders l relative to.
+func slogBaseLevel(l slog.Level) slog.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return slog.LevelDebug
+	case l < slog.LevelWarn:
+		return slog.LevelInfo
+	case l < slog.LevelError:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// lookupLevel parses a registered name or abbreviation, with an optional
+// offset, like "NOTICE+1" or "TRC-2".  s must be upper case.
+func lookupLevel(s string) (slog.Level, bool) {
+	name, offset := s, 0
+
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		var err error
+
+		name = s[:i]
+
+		offset, err = strconv.Atoi(s[i:])
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	levelRegistryMu.RLock()
+	defer levelRegistryMu.RUnlock()
+
+	for _, ln := range registeredLevels {
+		if ln.name == name || ln.abbrev == name {
+			return ln.level + slog.Level(offset), true
+		}
+	}
+
+	return 0, false
+}
+
+// expandLevelAbbreviation replaces a leading level abbreviation with the full level name.
+func expandLevelAbbreviation(s string) string {
+	name, rest := s, ""
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		name, rest = s[:i], s[i:]
+	}
+
+	levelRegistryMu.RLock()
+	defer levelRegistryMu.RUnlock()
+
+	for _, ln := range registeredLevels {
+		if ln.abbrev == name {
+			return ln.name + rest
+		}
+	}
+
+	return s
+}
+
+// hasCustomLevelName returns true if the level is a registered custom level,
+// rather than one of slog's levels, or an offset from one.
+func hasCustomLevelName(l slog.Level) bool {
+	_, ok := registeredLevel(l)
+
+	return ok && !isSlogLevel(l)
+}
@@ -0,0 +1,123 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterLevel(t *testing.T) {
+	t.Cleanup(resetLevels)
+
+	require.NoError(t, RegisterLevel(-12, "verbose", "vrb"))
+	require.NoError(t, RegisterLevel(16, "Emergency", ""))
+
+	l, err := parseLevel("verbose")
+	require.NoError(t, err)
+	assert.Equal(t, slog.Level(-12), l)
+
+	l, err = parseLevel("VRB+1")
+	require.NoError(t, err)
+	assert.Equal(t, slog.Level(-11), l)
+
+	l, err = parseLevel("emergency")
+	require.NoError(t, err)
+	assert.Equal(t, slog.Level(16), l)
+
+	assert.Equal(t, "VERBOSE", formatLevel(-12, false))
+	assert.Equal(t, "DBG-9", formatLevel(-13, true), "unregistered levels should be offsets from slog's levels")
+	assert.Equal(t, "EMERGENCY", formatLevel(16, true))
+	assert.Equal(t, "ERR+9", formatLevel(17, true))
+
+	// replace the name of a custom level
+	require.NoError(t, RegisterLevel(LevelFatal, "critical", "crt"))
+	assert.Equal(t, "CRITICAL", formatLevel(LevelFatal, false))
+
+	_, err = parseLevel("FATAL")
+	require.ErrorIs(t, err, ErrInvalidLevel)
+
+	invalid := []struct {
+		level        slog.Level
+		name, abbrev string
+	}{
+		{level: 20, name: ""},
+		{level: 20, name: "all"},
+		{level: 20, name: "off"},
+		{level: 20, name: "1st"},
+		{level: 20, name: "a-b"},
+		{level: 20, name: "a+b"},
+		{level: 20, name: "ok", abbrev: "a b"},
+		{level: 20, name: "info"},
+		{level: 20, name: "ok", abbrev: "trc"},
+		{level: slog.LevelInfo, name: "information"},
+	}
+
+	for _, tt := range invalid {
+		require.ErrorIs(t, RegisterLevel(tt.level, tt.name, tt.abbrev), ErrInvalidLevelName, "%v %q %q", tt.level, tt.name, tt.abbrev)
+	}
+}
+
+func TestFormatLevel(t *testing.T) {
+	tests := []struct {
+		level      slog.Level
+		full, abbr string
+	}{
+		{level: LevelTrace - 1, full: "DEBUG-5", abbr: "DBG-5"},
+		{level: LevelTrace, full: "TRACE", abbr: "TRC"},
+		{level: LevelTrace + 2, full: "DEBUG-2", abbr: "DBG-2"},
+		{level: slog.LevelDebug - 1, full: "DEBUG-1", abbr: "DBG-1"},
+		{level: slog.LevelDebug, full: "DEBUG", abbr: "DBG"},
+		{level: slog.LevelInfo, full: "INFO", abbr: "INF"},
+		{level: slog.LevelInfo + 1, full: "INFO+1", abbr: "INF+1"},
+		{level: LevelNotice, full: "NOTICE", abbr: "NTC"},
+		{level: slog.LevelInfo + 3, full: "INFO+3", abbr: "INF+3"},
+		{level: slog.LevelWarn, full: "WARN", abbr: "WRN"},
+		{level: slog.LevelError + 2, full: "ERROR+2", abbr: "ERR+2"},
+		{level: LevelFatal, full: "FATAL", abbr: "FTL"},
+		{level: slog.LevelError + 5, full: "ERROR+5", abbr: "ERR+5"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.full, formatLevel(tt.level, false))
+		assert.Equal(t, tt.abbr, formatLevel(tt.level, true))
+
+		l, err := parseLevel(tt.full)
+		require.NoError(t, err)
+		assert.Equal(t, tt.level, l)
+	}
+}
+
+func TestNameLevel(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	h := NewHandler(buf, &HandlerOptions{
+		Level:        LevelAll,
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), NameLevel},
+	})
+
+	l := slog.New(h)
+	l.Log(context.Background(), LevelFatal, "hi")
+	l.Log(context.Background(), LevelTrace, "hi")
+	l.Info("hi", "level", "not a level")
+
+	assert.Equal(t, "level=FATAL msg=hi\nlevel=TRACE msg=hi\nlevel=INFO msg=hi level=\"not a level\"\n", buf.String())
+}
+
+func TestTermHandler_customLevels(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	h := NewHandler(buf, &HandlerOptions{
+		Level:        LevelAll,
+		HandlerFn:    TermHandlerFn(),
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+	})
+
+	l := slog.New(h)
+	l.Log(context.Background(), LevelFatal, "hi")
+	l.Log(context.Background(), LevelTrace+1, "hi")
+	l.Warn("hi")
+
+	assert.Equal(t, "|FTL| hi\n|DBG-3| hi\n|WRN| hi\n", buf.String())
+}
@@ -0,0 +1,256 @@
+package flume
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+)
+
+// TruncatedKey is the key of the attr which LimitsMiddleware adds to records which were truncated.
+const TruncatedKey = "truncated"
+
+// DefaultTruncationMarker is the default Limits.Marker.
+const DefaultTruncationMarker = "…"
+
+// Limits configures LimitsMiddleware.  Zero values disable each limit.
+type Limits struct {
+	// MaxMessageLength is the maximum length of the message, in bytes.
+	MaxMessageLength int `json:"maxMessageLength,omitempty"`
+
+	// MaxStringLength is the maximum length of string values, in bytes.  It also
+	// applies to errors, fmt.Stringers, and byte slices, which are replaced with
+	// truncated strings if they exceed it.
+	MaxStringLength int `json:"maxStringLength,omitempty"`
+
+	// MaxAttrs is the maximum number of attrs in a record, and the maximum number of
+	// members in each group.  Attrs added with WithAttrs count toward the limit of the
+	// record, or of the group opened with WithGroup they were added to.  Excess attrs
+	// are dropped.
+	MaxAttrs int `json:"maxAttrs,omitempty"`
+
+	// MaxGroupDepth is the maximum depth of nested groups, including groups opened
+	// with WithGroup.  Groups nested deeper are replaced with the marker.
+	MaxGroupDepth int `json:"maxGroupDepth,omitempty"`
+
+	// Marker is appended to truncated strings, and replaces groups which are too
+	// deep.  Defaults to DefaultTruncationMarker.
+	Marker string `json:"marker,omitempty"`
+}
+
+// LimitsMiddleware returns middleware which enforces limits on the size of records,
+// to keep them within the line limits of log shippers.  Strings are truncated at a
+// UTF-8 boundary, and the marker is appended.  The lengths don't include the marker.
+//
+// When a record is truncated, a group named TruncatedKey is added to it, recording
+// what was cut:
+//
+//	"msg":     the original length of the message
+//	"strings": the number of strings which were truncated
+//	"attrs":   the number of attrs which were dropped
+//	"groups":  the number of groups which were replaced with the marker
+//
+// Only non-zero counts are included.  Attrs added with WithAttrs are also truncated,
+// but that isn't recorded.  Like other attrs added by middleware, if the logger has
+// open groups, the TruncatedKey group is added inside the innermost group.
+func LimitsMiddleware(limits Limits) Middleware {
+	if limits.Marker == "" {
+		limits.Marker = DefaultTruncationMarker
+	}
+
+	return MiddlewareFn(func(next slog.Handler) slog.Handler {
+		return &limitsHandler{limits: limits, next: next}
+	})
+}
+
+type truncation struct {
+	msg, strings, attrs, groups int
+}
+
+func (t truncation) attr() slog.Attr {
+	var attrs []slog.Attr
+
+	if t.msg > 0 {
+		attrs = append(attrs, slog.Int("msg", t.msg))
+	}
+
+	if t.strings > 0 {
+		attrs = append(attrs, slog.Int("strings", t.strings))
+	}
+
+	if t.attrs > 0 {
+		attrs = append(attrs, slog.Int("attrs", t.attrs))
+	}
+
+	if t.groups > 0 {
+		attrs = append(attrs, slog.Int("groups", t.groups))
+	}
+
+	return slog.Attr{Key: TruncatedKey, Value: slog.GroupValue(attrs...)}
+}
+
+type limitsHandler struct {
+	limits     Limits
+	next       slog.Handler
+	openGroups int
+	// number of attrs added with WithAttrs since the last WithGroup
+	added int
+}
+
+func (h *limitsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *limitsHandler) Handle(ctx context.Context, record slog.Record) error {
+	var t truncation
+
+	msg := record.Message
+	if h.limits.MaxMessageLength > 0 && len(msg) > h.limits.MaxMessageLength {
+		t.msg = len(msg)
+		msg = truncateString(msg, h.limits.MaxMessageLength) + h.limits.Marker
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	attrs = h.limitAttrs(h.dropExcessAttrs(attrs, &t), h.openGroups, &t)
+
+	if t == (truncation{}) {
+		return h.next.Handle(ctx, record)
+	}
+
+	limited := slog.NewRecord(record.Time, record.Level, msg, record.PC)
+	limited.AddAttrs(attrs...)
+	limited.AddAttrs(t.attr())
+
+	return h.next.Handle(ctx, limited)
+}
+
+func (h *limitsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var t truncation
+
+	attrs = h.limitAttrs(h.dropExcessAttrs(slices.Clone(attrs), &t), h.openGroups, &t)
+
+	return &limitsHandler{
+		limits:     h.limits,
+		next:       h.next.WithAttrs(attrs),
+		openGroups: h.openGroups,
+		added:      h.added + len(attrs),
+	}
+}
+
+func (h *limitsHandler) WithGroup(name string) slog.Handler {
+	return &limitsHandler{
+		limits:     h.limits,
+		next:       h.next.WithGroup(name),
+		openGroups: h.openGroups + 1,
+	}
+}
+
+func (h *limitsHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.next)
+}
+
+// dropExcessAttrs drops the attrs which don't fit in MaxAttrs, after the attrs added
+// with WithAttrs.
+func (h *limitsHandler) dropExcessAttrs(attrs []slog.Attr, t *truncation) []slog.Attr {
+	if h.limits.MaxAttrs <= 0 {
+		return attrs
+	}
+
+	room := max(h.limits.MaxAttrs-h.added, 0)
+	if len(attrs) > room {
+		t.attrs += len(attrs) - room
+		attrs = attrs[:room]
+	}
+
+	return attrs
+}
+
+// limitAttrs applies the limits to attrs at the given group depth, modifying attrs in place.
+func (h *limitsHandler) limitAttrs(attrs []slog.Attr, depth int, t *truncation) []slog.Attr {
+	if h.limits.MaxAttrs > 0 && len(attrs) > h.limits.MaxAttrs {
+		t.attrs += len(attrs) - h.limits.MaxAttrs
+		attrs = attrs[:h.limits.MaxAttrs]
+	}
+
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		switch a.Value.Kind() {
+		case slog.KindString:
+			if s, ok := h.limitString(a.Value.String()); ok {
+				a.Value = slog.StringValue(s)
+				t.strings++
+			}
+		case slog.KindAny:
+			if h.limits.MaxStringLength <= 0 {
+				break
+			}
+
+			if s, ok := h.limitString(stringish(a.Value.Any())); ok {
+				a.Value = slog.StringValue(s)
+				t.strings++
+			}
+		case slog.KindGroup:
+			groupDepth := depth
+			if a.Key != "" {
+				// groups with empty keys are inlined
+				groupDepth++
+			}
+
+			if h.limits.MaxGroupDepth > 0 && groupDepth > h.limits.MaxGroupDepth {
+				a.Value = slog.StringValue(h.limits.Marker)
+				t.groups++
+
+				break
+			}
+
+			a.Value = slog.GroupValue(h.limitAttrs(slices.Clone(a.Value.Group()), groupDepth, t)...)
+		}
+
+		attrs[i] = a
+	}
+
+	return attrs
+}
+
+// limitString returns the truncated string, and true, if s exceeds the MaxStringLength.
+func (h *limitsHandler) limitString(s string) (string, bool) {
+	if h.limits.MaxStringLength <= 0 || len(s) <= h.limits.MaxStringLength {
+		return "", false
+	}
+
+	return truncateString(s, h.limits.MaxStringLength) + h.limits.Marker, true
+}
+
+// stringish returns the string form of values which render as strings, like errors.
+func stringish(v any) string {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+// truncateString truncates s to at most n bytes, without splitting a UTF-8 sequence.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,160 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		log    func(l *slog.Logger)
+		want   string
+	}{
+		{
+			name:   "no limits",
+			limits: Limits{},
+			log: func(l *slog.Logger) {
+				l.Info(strings.Repeat("a", 10), "body", strings.Repeat("b", 10))
+			},
+			want: `{"level":"INFO","msg":"aaaaaaaaaa","body":"bbbbbbbbbb"}`,
+		},
+		{
+			name:   "within limits",
+			limits: Limits{MaxMessageLength: 10, MaxStringLength: 10, MaxAttrs: 1, MaxGroupDepth: 1},
+			log: func(l *slog.Logger) {
+				l.Info(strings.Repeat("a", 10), "g", slog.GroupValue(slog.String("body", strings.Repeat("b", 10))))
+			},
+			want: `{"level":"INFO","msg":"aaaaaaaaaa","g":{"body":"bbbbbbbbbb"}}`,
+		},
+		{
+			name:   "message",
+			limits: Limits{MaxMessageLength: 5},
+			log: func(l *slog.Logger) {
+				l.Info("hello world")
+			},
+			want: `{"level":"INFO","msg":"hello…","truncated":{"msg":11}}`,
+		},
+		{
+			name:   "strings",
+			limits: Limits{MaxStringLength: 4, Marker: "[cut]"},
+			log: func(l *slog.Logger) {
+				l.Info("hi",
+					"body", "hello world",
+					"short", "abc",
+					"utf8", "héllo",
+					"err", errors.New("boom boom"),
+					"bytes", []byte("abcdefg"),
+					"lvl", slog.LevelWarn,
+					"n", 123456789,
+					"g", slog.GroupValue(slog.String("body", "hello world")),
+				)
+			},
+			want: `{"level":"INFO","msg":"hi","body":"hell[cut]","short":"abc","utf8":"hél[cut]","err":"boom[cut]","bytes":"abcd[cut]",` +
+				`"lvl":"WARN","n":123456789,"g":{"body":"hell[cut]"},"truncated":{"strings":5}}`,
+		},
+		{
+			name:   "attrs",
+			limits: Limits{MaxAttrs: 2},
+			log: func(l *slog.Logger) {
+				l.Info("hi", "a", 1, "b", 2, "c", 3, "g", slog.GroupValue(slog.Int("d", 4), slog.Int("e", 5), slog.Int("f", 6)))
+			},
+			want: `{"level":"INFO","msg":"hi","a":1,"b":2,"truncated":{"attrs":2}}`,
+		},
+		{
+			name:   "with attrs count toward max attrs",
+			limits: Limits{MaxAttrs: 2},
+			log: func(l *slog.Logger) {
+				l.With("a", 1).With("b", 2, "c", 3).Info("x", "d", 4, "e", 5)
+			},
+			want: `{"level":"INFO","msg":"x","a":1,"b":2,"truncated":{"attrs":2}}`,
+		},
+		{
+			name:   "with attrs in open groups",
+			limits: Limits{MaxAttrs: 2},
+			log: func(l *slog.Logger) {
+				l.With("a", 1, "b", 2).WithGroup("req").With("c", 3).Info("x", "d", 4, "e", 5)
+			},
+			want: `{"level":"INFO","msg":"x","a":1,"b":2,"req":{"c":3,"d":4,"truncated":{"attrs":1}}}`,
+		},
+		{
+			name:   "group members",
+			limits: Limits{MaxAttrs: 2},
+			log: func(l *slog.Logger) {
+				l.Info("hi", "g", slog.GroupValue(slog.Int("d", 4), slog.Int("e", 5), slog.Int("f", 6)))
+			},
+			want: `{"level":"INFO","msg":"hi","g":{"d":4,"e":5},"truncated":{"attrs":1}}`,
+		},
+		{
+			name:   "group depth",
+			limits: Limits{MaxGroupDepth: 2},
+			log: func(l *slog.Logger) {
+				l.Info("hi",
+					slog.Group("a", slog.Group("b", slog.Group("c", "d", 1)), slog.Group("", slog.Group("e", "f", 2))),
+				)
+			},
+			want: `{"level":"INFO","msg":"hi","a":{"b":{"c":"…"},"e":{"f":2}},"truncated":{"groups":1}}`,
+		},
+		{
+			name:   "open groups count toward depth",
+			limits: Limits{MaxGroupDepth: 1},
+			log: func(l *slog.Logger) {
+				l.WithGroup("req").Info("hi", "a", 1, slog.Group("b", "c", 2))
+			},
+			want: `{"level":"INFO","msg":"hi","req":{"a":1,"b":"…","truncated":{"groups":1}}}`,
+		},
+		{
+			name:   "with attrs",
+			limits: Limits{MaxStringLength: 4},
+			log: func(l *slog.Logger) {
+				l.With("body", "hello world").Info("hi")
+			},
+			want: `{"level":"INFO","msg":"hi","body":"hell…"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := LimitsMiddleware(tt.limits).Apply(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeKeys(slog.TimeKey)}))
+
+			tt.log(slog.New(h))
+
+			assert.JSONEq(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 5))
+	assert.Equal(t, "abc", truncateString("abcdef", 3))
+	assert.Equal(t, "", truncateString("abc", 0))
+	assert.Equal(t, "h", truncateString("héllo", 2), "should not split multi-byte runes")
+	assert.Equal(t, "hé", truncateString("héllo", 3))
+}
+
+func TestHandlerOptions_Limits(t *testing.T) {
+	var opts HandlerOptions
+
+	require.NoError(t, json.Unmarshal([]byte(`{"handler":"text","limits":{"maxMessageLength":2,"marker":"..."}}`), &opts))
+	assert.Equal(t, &Limits{MaxMessageLength: 2, Marker: "..."}, opts.Limits)
+
+	clone := opts.Clone()
+	clone.Limits.MaxMessageLength = 3
+	assert.Equal(t, 2, opts.Limits.MaxMessageLength, "clone should not share limits")
+
+	buf := bytes.NewBuffer(nil)
+	opts.ReplaceAttrs = []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)}
+
+	slog.New(NewHandler(buf, &opts)).Info("hello")
+
+	assert.Equal(t, "level=INFO msg=he... truncated.msg=5\n", buf.String())
+}
@@ -0,0 +1,267 @@
+package flume
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics is middleware which counts records by logger name and level, and counts the
+// records which the next handler failed to write, or dropped.  The counts are exposed
+// in the Prometheus text exposition format, by ServeHTTP, and as JSON, by String, so
+// Metrics can be published with expvar:
+//
+//	metrics := flume.NewMetrics()
+//
+//	flume.Default().SetHandlerOptions(&flume.HandlerOptions{
+//		Middleware: []flume.Middleware{metrics},
+//	})
+//
+//	expvar.Publish("logging", metrics)
+//	http.Handle("/metrics/logging", metrics)
+//
+// The logger name is the value of the LoggerKey attr, as set by flume.New or Handler.Named.
+// Records from loggers without a name are counted with an empty name.  Levels are named
+// like NameLevel names them, i.e. "INFO", "FATAL", or "WARN+1".
+//
+// Records are counted after the level filtering of the handler it wraps, so it only counts
+// records which are logged.  Records are counted as failed if the next handler returns an
+// error, or as dropped if the error wraps ErrDropped.
+type Metrics struct {
+	mu     sync.RWMutex
+	series map[metricsKey]*metricsCounts
+}
+
+type metricsKey struct {
+	logger string
+	level  slog.Level
+}
+
+type metricsCounts struct {
+	records atomic.Uint64
+	failed  atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewMetrics returns a new, empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{series: map[metricsKey]*metricsCounts{}}
+}
+
+// Apply implements Middleware.
+func (m *Metrics) Apply(next slog.Handler) slog.Handler {
+	return &metricsHandler{metrics: m, next: next}
+}
+
+func (m *Metrics) counts(logger string, level slog.Level) *metricsCounts {
+	key := metricsKey{logger: logger, level: level}
+
+	m.mu.RLock()
+	c, ok := m.series[key]
+	m.mu.RUnlock()
+
+	if ok {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok = m.series[key]
+	if !ok {
+		c = &metricsCounts{}
+		m.series[key] = c
+	}
+
+	return c
+}
+
+type metricsSample struct {
+	logger                   string
+	level                    string
+	records, failed, dropped uint64
+}
+
+// snapshot returns the current counts, sorted by logger and level.
+func (m *Metrics) snapshot() []metricsSample {
+	m.mu.RLock()
+
+	keys := make([]metricsKey, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+
+	slices.SortFunc(keys, func(a, b metricsKey) int {
+		return cmp.Or(cmp.Compare(a.logger, b.logger), cmp.Compare(a.level, b.level))
+	})
+
+	samples := make([]metricsSample, 0, len(keys))
+	for _, k := range keys {
+		c := m.series[k]
+		samples = append(samples, metricsSample{
+			logger:  k.logger,
+			level:   formatLevel(k.level, false),
+			records: c.records.Load(),
+			failed:  c.failed.Load(),
+			dropped: c.dropped.Load(),
+		})
+	}
+
+	m.mu.RUnlock()
+
+	return samples
+}
+
+// ServeHTTP renders the counts in the Prometheus text exposition format:
+//
+//	# HELP flume_records_total Records logged, by logger and level.
+//	# TYPE flume_records_total counter
+//	flume_records_total{logger="http",level="ERROR"} 3
+//	# HELP flume_failed_records_total Records which failed to be written, by logger and level.
+//	# TYPE flume_failed_records_total counter
+//	flume_failed_records_total{logger="http",level="ERROR"} 0
+//	# HELP flume_dropped_records_total Records dropped by the handler, by logger and level.
+//	# TYPE flume_dropped_records_total counter
+//	flume_dropped_records_total{logger="http",level="ERROR"} 0
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	samples := m.snapshot()
+
+	var buf bytes.Buffer
+
+	metrics := []struct {
+		name, help string
+		value      func(metricsSample) uint64
+	}{
+		{"flume_records_total", "Records logged, by logger and level.", func(s metricsSample) uint64 { return s.records }},
+		{"flume_failed_records_total", "Records which failed to be written, by logger and level.", func(s metricsSample) uint64 { return s.failed }},
+		{"flume_dropped_records_total", "Records dropped by the handler, by logger and level.", func(s metricsSample) uint64 { return s.dropped }},
+	}
+
+	for _, metric := range metrics {
+		buf.WriteString("# HELP " + metric.name + " " + metric.help + "\n")
+		buf.WriteString("# TYPE " + metric.name + " counter\n")
+
+		for _, s := range samples {
+			buf.WriteString(metric.name + `{logger="` + escapeLabelValue(s.logger) + `",level="` + escapeLabelValue(s.level) + `"} `)
+			buf.WriteString(strconv.FormatUint(metric.value(s), 10))
+			buf.WriteByte('\n')
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+// String implements expvar.Var.  It renders the counts as a JSON object, keyed
+// by logger name, then level:
+//
+//	{"http":{"ERROR":{"records":3,"failed":0,"dropped":0}}}
+func (m *Metrics) String() string {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	logger := ""
+
+	for i, s := range m.snapshot() {
+		switch {
+		case i == 0:
+			appendJSONString(&buf, s.logger)
+			buf.WriteString(":{")
+		case s.logger != logger:
+			buf.WriteString("},")
+			appendJSONString(&buf, s.logger)
+			buf.WriteString(":{")
+		default:
+			buf.WriteByte(',')
+		}
+
+		logger = s.logger
+
+		appendJSONString(&buf, s.level)
+		buf.WriteString(`:{"records":` + strconv.FormatUint(s.records, 10) +
+			`,"failed":` + strconv.FormatUint(s.failed, 10) +
+			`,"dropped":` + strconv.FormatUint(s.dropped, 10) + "}")
+	}
+
+	if buf.Len() > 1 {
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte('}')
+
+	return buf.String()
+}
+
+type metricsHandler struct {
+	metrics    *Metrics
+	next       slog.Handler
+	logger     string
+	openGroups int
+}
+
+func (h *metricsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *metricsHandler) Handle(ctx context.Context, record slog.Record) error {
+	c := h.metrics.counts(h.logger, record.Level)
+	c.records.Add(1)
+
+	err := h.next.Handle(ctx, record)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrDropped):
+		c.dropped.Add(1)
+	default:
+		c.failed.Add(1)
+	}
+
+	return err
+}
+
+func (h *metricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	logger := h.logger
+
+	// the logger name attribute is not allowed to be nested in a group
+	if h.openGroups == 0 {
+		if name := loggerName(attrs); name != "" {
+			logger = name
+		}
+	}
+
+	return &metricsHandler{
+		metrics:    h.metrics,
+		next:       h.next.WithAttrs(attrs),
+		logger:     logger,
+		openGroups: h.openGroups,
+	}
+}
+
+func (h *metricsHandler) WithGroup(name string) slog.Handler {
+	return &metricsHandler{
+		metrics:    h.metrics,
+		next:       h.next.WithGroup(name),
+		logger:     h.logger,
+		openGroups: h.openGroups + 1,
+	}
+}
+
+func (h *metricsHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.next)
+}
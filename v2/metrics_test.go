@@ -0,0 +1,139 @@
+package flume
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+
+	var handleErr error
+
+	h := NewHandler(io.Discard, &HandlerOptions{
+		Level: slog.LevelDebug,
+		Levels: Levels{
+			"sql": slog.LevelWarn,
+		},
+		Middleware: []Middleware{
+			metrics,
+			SimpleMiddlewareFn(func(ctx context.Context, record slog.Record, next slog.Handler) error {
+				if handleErr != nil {
+					return handleErr
+				}
+
+				return next.Handle(ctx, record)
+			}),
+		},
+	})
+
+	l := slog.New(h)
+	l.Info("hi")
+	l.Info("hi")
+	l.Log(context.Background(), LevelFatal, "hi")
+
+	httpLog := slog.New(h.Named("http"))
+	httpLog.Error("hi")
+	httpLog.WithGroup("req").With(LoggerKey, "not a logger name").Error("hi")
+
+	sql := slog.New(h.Named("sql"))
+	sql.Info("filtered by level")
+	sql.Warn("hi")
+
+	handleErr = errors.New("broken pipe")
+
+	sql.Warn("hi")
+
+	handleErr = ErrDropped
+
+	httpLog.Error("hi")
+
+	assert.JSONEq(t, `{
+		"":{
+			"INFO":{"records":2,"failed":0,"dropped":0},
+			"FATAL":{"records":1,"failed":0,"dropped":0}
+		},
+		"http":{
+			"ERROR":{"records":3,"failed":0,"dropped":1}
+		},
+		"sql":{
+			"WARN":{"records":2,"failed":1,"dropped":0}
+		}
+	}`, metrics.String())
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP flume_records_total Records logged, by logger and level.
+# TYPE flume_records_total counter
+flume_records_total{logger="",level="INFO"} 2
+flume_records_total{logger="",level="FATAL"} 1
+flume_records_total{logger="http",level="ERROR"} 3
+flume_records_total{logger="sql",level="WARN"} 2
+# HELP flume_failed_records_total Records which failed to be written, by logger and level.
+# TYPE flume_failed_records_total counter
+flume_failed_records_total{logger="",level="INFO"} 0
+flume_failed_records_total{logger="",level="FATAL"} 0
+flume_failed_records_total{logger="http",level="ERROR"} 0
+flume_failed_records_total{logger="sql",level="WARN"} 1
+# HELP flume_dropped_records_total Records dropped by the handler, by logger and level.
+# TYPE flume_dropped_records_total counter
+flume_dropped_records_total{logger="",level="INFO"} 0
+flume_dropped_records_total{logger="",level="FATAL"} 0
+flume_dropped_records_total{logger="http",level="ERROR"} 1
+flume_dropped_records_total{logger="sql",level="WARN"} 0
+`, rec.Body.String())
+}
+
+func TestMetrics_empty(t *testing.T) {
+	metrics := NewMetrics()
+
+	assert.JSONEq(t, `{}`, metrics.String())
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, `# HELP flume_records_total Records logged, by logger and level.
+# TYPE flume_records_total counter
+# HELP flume_failed_records_total Records which failed to be written, by logger and level.
+# TYPE flume_failed_records_total counter
+# HELP flume_dropped_records_total Records dropped by the handler, by logger and level.
+# TYPE flume_dropped_records_total counter
+`, rec.Body.String())
+}
+
+// expvarTestRuns makes the name published by TestMetrics_expvar unique, since expvar
+// panics if a name is published twice, i.e. with -count=2.
+var expvarTestRuns atomic.Int64
+
+func TestMetrics_expvar(t *testing.T) {
+	name := "flume_test_metrics_" + strconv.FormatInt(expvarTestRuns.Add(1), 10)
+
+	metrics := NewMetrics()
+	expvar.Publish(name, metrics)
+
+	l := slog.New(metrics.Apply(slog.NewTextHandler(io.Discard, nil))).With(LoggerKey, "a \"quoted\"\nname")
+	l.Info("hi")
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &m))
+	assert.Equal(t, map[string]any{"a \"quoted\"\nname": map[string]any{"INFO": map[string]any{"records": float64(1), "failed": float64(0), "dropped": float64(0)}}}, m)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), `flume_records_total{logger="a \"quoted\"\nname",level="INFO"} 1`+"\n")
+}
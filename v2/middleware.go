@@ -98,6 +98,15 @@ func (r *ReplaceAttrsMiddleware) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return r.clone(r.next.WithAttrs(attrs))
 }
 
+// Flush flushes the next handler, if it implements Flusher.
+func (r *ReplaceAttrsMiddleware) Flush(ctx context.Context) error {
+	if r.next == nil {
+		return nil
+	}
+
+	return flushHandler(ctx, r.next)
+}
+
 func (r *ReplaceAttrsMiddleware) WithGroup(name string) slog.Handler {
 	r = r.clone(r.next.WithGroup(name))
 	r.groups = append(r.groups, name)
@@ -232,3 +241,7 @@ func (h *middlewareHandler) WithGroup(name string) slog.Handler {
 		middleware: h.middleware,
 	}
 }
+
+func (h *middlewareHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.next)
+}
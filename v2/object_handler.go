@@ -0,0 +1,254 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// objectHandler is the base for handlers which render each record as a single
+// object, like the pretty json and binary handlers.  It collects the record's
+// built-in fields and attrs into an attrObject tree, applying ReplaceAttr and
+// slog's rules for groups, then hands the record and the tree to an encode function.
+// If the encode function doesn't write anything, nothing is written to w.
+type objectHandler struct {
+	opts     slog.HandlerOptions
+	w        io.Writer
+	mu       *sync.Mutex
+	groups   []string
+	preAttrs []groupedAttrs
+	encode   func(buf *bytes.Buffer, record slog.Record, obj *attrObject) error
+	// flush, if set, replaces flushing w
+	flush func(ctx context.Context) error
+}
+
+type groupedAttrs struct {
+	groups []string
+	attrs  []slog.Attr
+}
+
+func newObjectHandler(w io.Writer, opts *slog.HandlerOptions, encode func(*bytes.Buffer, slog.Record, *attrObject) error) *objectHandler {
+	h := &objectHandler{
+		w:      w,
+		mu:     &sync.Mutex{},
+		encode: encode,
+	}
+
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	return h
+}
+
+func (h *objectHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *objectHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := *h
+	h2.preAttrs = append(slices.Clip(h.preAttrs), groupedAttrs{groups: h.groups, attrs: slices.Clone(attrs)})
+
+	return &h2
+}
+
+func (h *objectHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+
+	return &h2
+}
+
+func (h *objectHandler) Handle(_ context.Context, record slog.Record) error {
+	root := &attrObject{}
+
+	if !record.Time.IsZero() {
+		h.addBuiltin(root, slog.Time(slog.TimeKey, record.Time))
+	}
+
+	h.addBuiltin(root, slog.Any(slog.LevelKey, record.Level))
+
+	if h.opts.AddSource && record.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{record.PC})
+		f, _ := fs.Next()
+		h.addBuiltin(root, slog.Any(slog.SourceKey, &slog.Source{
+			Function: f.Function,
+			File:     f.File,
+			Line:     f.Line,
+		}))
+	}
+
+	h.addBuiltin(root, slog.String(slog.MessageKey, record.Message))
+
+	for _, ga := range h.preAttrs {
+		h.addAttrs(root.group(ga.groups), ga.groups, ga.attrs)
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	h.addAttrs(root.group(h.groups), h.groups, attrs)
+
+	// elide empty groups
+	root.prune()
+
+	var buf bytes.Buffer
+
+	err := h.encode(&buf, record, root)
+	if err != nil {
+		return err
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err = h.w.Write(buf.Bytes())
+
+	return err //nolint:wrapcheck
+}
+
+// Flush implements Flusher.
+func (h *objectHandler) Flush(ctx context.Context) error {
+	if h.flush != nil {
+		return h.flush(ctx)
+	}
+
+	return flushWriter(ctx, h.w)
+}
+
+func (h *objectHandler) addBuiltin(obj *attrObject, a slog.Attr) {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Key == "" {
+		return
+	}
+
+	obj.set(a.Key, a.Value)
+}
+
+func (h *objectHandler) addAttrs(obj *attrObject, groups []string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+			a = h.opts.ReplaceAttr(groups, a)
+			a.Value = a.Value.Resolve()
+		}
+
+		switch {
+		case a.Value.Kind() == slog.KindGroup:
+			members := a.Value.Group()
+			if len(members) == 0 {
+				continue
+			}
+
+			if a.Key == "" {
+				// inline the group members
+				h.addAttrs(obj, groups, members)
+				continue
+			}
+
+			h.addAttrs(obj.child(a.Key), append(slices.Clip(groups), a.Key), members)
+		case a.Equal(slog.Attr{}):
+			continue
+		default:
+			obj.set(a.Key, a.Value)
+		}
+	}
+}
+
+// attrObject is an insertion-ordered object.  Values are either
+// *attrObject or slog.Value.
+type attrObject struct {
+	keys []string
+	vals map[string]any
+}
+
+func (o *attrObject) set(key string, v any) {
+	if o.vals == nil {
+		o.vals = map[string]any{}
+	}
+
+	if _, ok := o.vals[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.vals[key] = v
+}
+
+// child returns the nested object with the given key, creating it if necessary.
+func (o *attrObject) child(key string) *attrObject {
+	if c, ok := o.vals[key].(*attrObject); ok {
+		return c
+	}
+
+	c := &attrObject{}
+	o.set(key, c)
+
+	return c
+}
+
+// group returns the object nested under the path of group names.
+func (o *attrObject) group(groups []string) *attrObject {
+	for _, g := range groups {
+		o = o.child(g)
+	}
+
+	return o
+}
+
+// prune recursively removes empty nested objects, mirroring slog's
+// rule that empty groups are elided.
+func (o *attrObject) prune() {
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool {
+		if c, ok := o.vals[k].(*attrObject); ok {
+			c.prune()
+
+			if len(c.keys) == 0 {
+				delete(o.vals, k)
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// remove deletes key from the object, and returns its value, if any.
+func (o *attrObject) remove(key string) (any, bool) {
+	v, ok := o.vals[key]
+	if !ok {
+		return nil, false
+	}
+
+	delete(o.vals, key)
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+
+	return v, true
+}
@@ -0,0 +1,530 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPEncoding selects the encoding of OTLP/HTTP export requests.
+type OTLPEncoding int
+
+const (
+	// OTLPProtobuf encodes requests as binary protobuf.
+	OTLPProtobuf OTLPEncoding = iota
+	// OTLPJSON encodes requests as JSON.
+	OTLPJSON
+)
+
+// DefaultOTLPEndpoint is the default OTLP/HTTP logs endpoint of a local collector.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/logs"
+
+var (
+	// ErrOTLPExport is returned when the collector rejects an export request,
+	// or the request fails after all retries.
+	ErrOTLPExport = errors.New("otlp export failed")
+	// ErrOTLPQueueFull is returned by the handler when the record is dropped
+	// because the export queue is full.  It is wrapped with ErrDropped.
+	ErrOTLPQueueFull = errors.New("otlp export queue full")
+	// ErrOTLPExporterClosed is returned by the handler after the exporter is shut down.
+	// It is wrapped with ErrDropped.
+	ErrOTLPExporterClosed = errors.New("otlp exporter closed")
+)
+
+// OTLPOptions configures an OTLPExporter.
+type OTLPOptions struct {
+	// Endpoint is the URL of the collector's logs endpoint.  Defaults to DefaultOTLPEndpoint.
+	Endpoint string
+
+	// Encoding defaults to OTLPProtobuf.
+	Encoding OTLPEncoding
+
+	// Headers are added to each export request, i.e. for authentication.
+	Headers map[string]string
+
+	// Resource holds the attributes of the resource which produced the logs.  If it
+	// doesn't contain a "service.name" attr, one is added, with the value
+	// "unknown_service:" followed by the base name of the program.
+	Resource []slog.Attr
+
+	// BatchSize is the maximum number of records in each export request.  A batch
+	// is exported as soon as it is full.  Defaults to 512.
+	BatchSize int
+
+	// FlushInterval is the maximum time records wait before they are exported.  Defaults
+	// to 5 seconds.
+	FlushInterval time.Duration
+
+	// MaxQueueSize is the maximum number of records waiting to be exported.  Records
+	// logged while the queue is full are dropped.  Defaults to 2048.
+	MaxQueueSize int
+
+	// MaxRetries is the number of times a failed export is retried.  Requests which fail
+	// with a network error, or with a 429, 502, 503, or 504 status, are retried with
+	// exponential backoff, honoring the Retry-After header.  Defaults to 5.  Set to
+	// a negative value to disable retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry.  It doubles with each retry,
+	// up to 30 seconds.  Defaults to 1 second.
+	RetryBackoff time.Duration
+
+	// Client is used to send export requests.  Defaults to an http.Client with
+	// a 10 second timeout.
+	Client *http.Client
+
+	// OnError, if set, is called with the errors from exports in the background.
+	OnError func(error)
+}
+
+// OTLPExporter batches records and exports them to an OpenTelemetry collector,
+// using the OTLP/HTTP protocol.  Create handlers which write to the exporter with
+// NewOTLPHandler, or HandlerFn:
+//
+//	exp := flume.NewOTLPExporter(&flume.OTLPOptions{Endpoint: "http://collector:4318/v1/logs"})
+//	defer exp.Shutdown(context.Background())
+//
+//	flume.RegisterHandlerFn("otlp", exp.HandlerFn())
+//
+// Records are exported in the background, so the exporter should be shut down
+// before the program exits, to export the remaining records.
+type OTLPExporter struct {
+	opts     OTLPOptions
+	resource []otlpKeyValue
+
+	mu      sync.Mutex
+	queue   []otlpQueued
+	started bool
+	closed  bool
+
+	// exportMu serializes exports
+	exportMu sync.Mutex
+
+	kick   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	ctx    context.Context //nolint:containedctx // cancels background exports on shutdown
+	cancel context.CancelFunc
+}
+
+type otlpQueued struct {
+	scope  string
+	record otlpLogRecord
+}
+
+// NewOTLPExporter returns a new OTLPExporter.  opts may be nil.  The background
+// export goroutine is started when the first record is logged.
+func NewOTLPExporter(opts *OTLPOptions) *OTLPExporter {
+	var o OTLPOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Endpoint == "" {
+		o.Endpoint = DefaultOTLPEndpoint
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = 512
+	}
+
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+
+	if o.MaxQueueSize <= 0 {
+		o.MaxQueueSize = 2048
+	}
+
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 5
+	}
+
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = time.Second
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resource := slices.Clone(o.Resource)
+	if !slices.ContainsFunc(resource, func(a slog.Attr) bool { return a.Key == "service.name" }) {
+		resource = append(resource, slog.String("service.name", "unknown_service:"+filepath.Base(os.Args[0])))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &OTLPExporter{
+		opts:     o,
+		resource: otlpAttrKeyValues(resource),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// NewOTLPHandler returns a slog.Handler which converts records to OTLP LogRecords, and
+// queues them for export by e.  opts may be nil.
+//
+// The level is mapped to the SeverityNumber, the message to the body, and the value
+// of the LoggerKey attr to the instrumentation scope name.  The remaining attrs are
+// mapped to attributes, with groups mapped to nested key-value lists.  With AddSource,
+// the source is mapped to the code.file.path, code.line.number, and code.function.name
+// attributes.
+func NewOTLPHandler(e *OTLPExporter, opts *slog.HandlerOptions) slog.Handler {
+	h := newObjectHandler(nil, opts, func(_ *bytes.Buffer, record slog.Record, obj *attrObject) error {
+		return e.enqueue(record, obj)
+	})
+	h.flush = e.Flush
+
+	return h
+}
+
+// HandlerFn returns a HandlerFn which creates handlers with NewOTLPHandler.
+func (e *OTLPExporter) HandlerFn() HandlerFn {
+	return func(_ string, _ io.Writer, opts *slog.HandlerOptions) slog.Handler {
+		return NewOTLPHandler(e, opts)
+	}
+}
+
+func (e *OTLPExporter) enqueue(record slog.Record, obj *attrObject) error {
+	rec := otlpLogRecord{
+		observedTimeUnixNano: uint64(time.Now().UnixNano()), //nolint:gosec // times before the epoch aren't expected
+		severityNumber:       otlpSeverityNumber(record.Level),
+		body:                 record.Message,
+	}
+
+	if v, ok := obj.remove(slog.TimeKey); ok {
+		if v, ok := v.(slog.Value); ok && v.Kind() == slog.KindTime {
+			rec.timeUnixNano = uint64(v.Time().UnixNano()) //nolint:gosec // times before the epoch aren't expected
+		}
+	}
+
+	if v, ok := obj.remove(slog.LevelKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			rec.severityText = v.String()
+		}
+	}
+
+	if v, ok := obj.remove(slog.MessageKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			rec.body = v.String()
+		}
+	}
+
+	var scope string
+	if v, ok := obj.remove(LoggerKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			scope = v.String()
+		}
+	}
+
+	if v, ok := obj.remove(slog.SourceKey); ok {
+		if v, ok := v.(slog.Value); ok && v.Kind() == slog.KindAny {
+			if src, ok := v.Any().(*slog.Source); ok {
+				rec.attrs = append(rec.attrs,
+					otlpKeyValue{key: "code.file.path", value: otlpValue{kind: otlpString, str: src.File}},
+					otlpKeyValue{key: "code.line.number", value: otlpValue{kind: otlpInt, num: int64(src.Line)}},
+					otlpKeyValue{key: "code.function.name", value: otlpValue{kind: otlpString, str: src.Function}},
+				)
+			}
+		}
+	}
+
+	rec.attrs = append(rec.attrs, otlpObjectKeyValues(obj)...)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return fmt.Errorf("%w: %w", ErrDropped, ErrOTLPExporterClosed)
+	}
+
+	if len(e.queue) >= e.opts.MaxQueueSize {
+		return fmt.Errorf("%w: %w", ErrDropped, ErrOTLPQueueFull)
+	}
+
+	e.queue = append(e.queue, otlpQueued{scope: scope, record: rec})
+
+	if !e.started {
+		e.started = true
+
+		go e.run()
+	}
+
+	if len(e.queue) >= e.opts.BatchSize {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-e.stop:
+			return
+		case <-e.kick:
+			err = e.export(e.ctx, false)
+		case <-ticker.C:
+			err = e.export(e.ctx, true)
+		}
+
+		// errors caused by shutdown are handled by Shutdown
+		if err != nil && e.ctx.Err() == nil && e.opts.OnError != nil {
+			e.opts.OnError(err)
+		}
+	}
+}
+
+// Flush exports all queued records, and waits for the exports to complete.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	return e.export(ctx, true)
+}
+
+// Shutdown stops the background exports, then exports the remaining records.  Records
+// logged after Shutdown are dropped.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+
+	e.closed = true
+	started := e.started
+	e.mu.Unlock()
+
+	if started {
+		close(e.stop)
+		// abort any background export which is waiting to retry.  Its batch is
+		// returned to the queue, and exported below.
+		e.cancel()
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return fmt.Errorf("shutting down otlp exporter: %w", ctx.Err())
+		}
+	}
+
+	return e.Flush(ctx)
+}
+
+// export sends the queued records in batches.  If all is false, only full
+// batches are sent.
+func (e *OTLPExporter) export(ctx context.Context, all bool) error {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	var errs []error
+
+	for {
+		e.mu.Lock()
+
+		n := min(len(e.queue), e.opts.BatchSize)
+		if n == 0 || (!all && n < e.opts.BatchSize) {
+			e.mu.Unlock()
+			break
+		}
+
+		batch := slices.Clone(e.queue[:n])
+		e.queue = slices.Delete(e.queue, 0, n)
+		e.mu.Unlock()
+
+		err := e.send(ctx, batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				// put the batch back, so it can be retried later
+				e.mu.Lock()
+				e.queue = slices.Concat(batch, e.queue)
+				e.mu.Unlock()
+
+				return errors.Join(append(errs, err)...)
+			}
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *OTLPExporter) send(ctx context.Context, batch []otlpQueued) error {
+	body, contentType := e.encodeRequest(batch)
+	backoff := e.opts.RetryBackoff
+
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+
+		retryAfter, err = e.post(ctx, body, contentType)
+		if err == nil || retryAfter < 0 || attempt >= e.opts.MaxRetries {
+			break
+		}
+
+		if retryAfter == 0 {
+			retryAfter = backoff
+			backoff = min(backoff*2, 30*time.Second)
+		}
+
+		timer := time.NewTimer(retryAfter)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrOTLPExport, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+// post sends a single export request.  If the request fails and should be retried,
+// it returns the delay requested by the server, or 0.  If it shouldn't be retried,
+// it returns -1.
+func (e *OTLPExporter) post(ctx context.Context, body []byte, contentType string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return -1, fmt.Errorf("%w: %w", ErrOTLPExport, err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrOTLPExport, err)
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("%w: %s responded %s", ErrOTLPExport, e.opts.Endpoint, resp.Status)
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && secs > 0 {
+			return time.Duration(secs) * time.Second, err
+		}
+
+		return 0, err
+	default:
+		return -1, err
+	}
+}
+
+// encodeRequest encodes an ExportLogsServiceRequest, with a ScopeLogs for
+// each distinct scope in the batch.
+func (e *OTLPExporter) encodeRequest(batch []otlpQueued) ([]byte, string) {
+	var scopes []string
+
+	byScope := map[string][]*otlpLogRecord{}
+
+	for i := range batch {
+		q := &batch[i]
+		if _, ok := byScope[q.scope]; !ok {
+			scopes = append(scopes, q.scope)
+		}
+
+		byScope[q.scope] = append(byScope[q.scope], &q.record)
+	}
+
+	if e.opts.Encoding == OTLPJSON {
+		var buf bytes.Buffer
+
+		buf.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+		appendOTLPJSONKeyValues(&buf, e.resource)
+		buf.WriteString(`},"scopeLogs":[`)
+
+		for i, scope := range scopes {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteString(`{"scope":{`)
+
+			if scope != "" {
+				buf.WriteString(`"name":`)
+				appendJSONString(&buf, scope)
+			}
+
+			buf.WriteString(`},"logRecords":[`)
+
+			for j, rec := range byScope[scope] {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+
+				rec.appendJSON(&buf)
+			}
+
+			buf.WriteString(`]}`)
+		}
+
+		buf.WriteString(`]}]}`)
+
+		return buf.Bytes(), "application/json"
+	}
+
+	body := appendProtoMessage(nil, 1, func(b []byte) []byte {
+		b = appendProtoMessage(b, 1, func(b []byte) []byte {
+			return appendProtoKeyValues(b, 1, e.resource)
+		})
+
+		for _, scope := range scopes {
+			b = appendProtoMessage(b, 2, func(b []byte) []byte {
+				b = appendProtoMessage(b, 1, func(b []byte) []byte {
+					if scope == "" {
+						return b
+					}
+
+					return appendProtoString(b, 1, scope)
+				})
+
+				for _, rec := range byScope[scope] {
+					b = appendProtoMessage(b, 2, rec.appendProto)
+				}
+
+				return b
+			})
+		}
+
+		return b
+	})
+
+	return body, "application/x-protobuf"
+}
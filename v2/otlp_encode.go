@@ -0,0 +1,401 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// otlpValue is an OTLP AnyValue.  The zero value is an empty AnyValue.
+type otlpValue struct {
+	kind  otlpKind
+	str   string
+	num   int64
+	float float64
+	bytes []byte
+	array []otlpValue
+	kvs   []otlpKeyValue
+}
+
+type otlpKind int
+
+const (
+	otlpEmpty otlpKind = iota
+	otlpString
+	otlpBool
+	otlpInt
+	otlpDouble
+	otlpArray
+	otlpKVList
+	otlpBytes
+)
+
+type otlpKeyValue struct {
+	key   string
+	value otlpValue
+}
+
+// otlpLogRecord is an OTLP LogRecord.
+type otlpLogRecord struct {
+	timeUnixNano         uint64
+	observedTimeUnixNano uint64
+	severityNumber       int
+	severityText         string
+	body                 string
+	attrs                []otlpKeyValue
+}
+
+// otlpSeverityNumber maps slog levels to OTLP severity numbers.  slog's levels
+// are spaced 4 apart, like the OTLP severity ranges, so LevelDebug maps to
+// DEBUG (5), LevelInfo to INFO (9), LevelWarn to WARN (13), and LevelError to
+// ERROR (17).
+func otlpSeverityNumber(l slog.Level) int {
+	return min(max(int(l)+9, 1), 24)
+}
+
+func otlpObjectKeyValues(obj *attrObject) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(obj.keys))
+
+	for _, k := range obj.keys {
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			kvs = append(kvs, otlpKeyValue{key: k, value: otlpValue{kind: otlpKVList, kvs: otlpObjectKeyValues(v)}})
+		case slog.Value:
+			kvs = append(kvs, otlpKeyValue{key: k, value: otlpSlogValue(v)})
+		}
+	}
+
+	return kvs
+}
+
+func otlpAttrKeyValues(attrs []slog.Attr) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			kvs = append(kvs, otlpKeyValue{key: a.Key, value: otlpValue{kind: otlpKVList, kvs: otlpAttrKeyValues(v.Group())}})
+			continue
+		}
+
+		kvs = append(kvs, otlpKeyValue{key: a.Key, value: otlpSlogValue(v)})
+	}
+
+	return kvs
+}
+
+// otlpSlogValue converts a slog value to an AnyValue.  Durations are converted
+// to nanoseconds, and times to nanoseconds since the epoch, like the OpenTelemetry
+// slog bridge.
+func otlpSlogValue(v slog.Value) otlpValue {
+	switch v.Kind() {
+	case slog.KindString:
+		return otlpValue{kind: otlpString, str: v.String()}
+	case slog.KindInt64:
+		return otlpValue{kind: otlpInt, num: v.Int64()}
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			return otlpValue{kind: otlpInt, num: int64(u)}
+		}
+
+		return otlpValue{kind: otlpDouble, float: float64(v.Uint64())}
+	case slog.KindFloat64:
+		return otlpValue{kind: otlpDouble, float: v.Float64()}
+	case slog.KindBool:
+		return otlpBoolValue(v.Bool())
+	case slog.KindDuration:
+		return otlpValue{kind: otlpInt, num: int64(v.Duration())}
+	case slog.KindTime:
+		return otlpValue{kind: otlpInt, num: v.Time().UnixNano()}
+	case slog.KindGroup:
+		return otlpValue{kind: otlpKVList, kvs: otlpAttrKeyValues(v.Group())}
+	default:
+		return otlpAnyValue(v.Any())
+	}
+}
+
+func otlpBoolValue(b bool) otlpValue {
+	var n int64
+	if b {
+		n = 1
+	}
+
+	return otlpValue{kind: otlpBool, num: n}
+}
+
+func otlpAnyValue(a any) otlpValue {
+	switch a := a.(type) {
+	case nil:
+		return otlpValue{}
+	case slog.Level:
+		return otlpValue{kind: otlpString, str: a.String()}
+	case *slog.Source:
+		return otlpValue{kind: otlpString, str: syslogValueString(slog.AnyValue(a))}
+	case []byte:
+		return otlpValue{kind: otlpBytes, bytes: a}
+	case json.Marshaler:
+	case error:
+		return otlpValue{kind: otlpString, str: a.Error()}
+	}
+
+	// fall back on converting the value like encoding/json would
+	b, err := json.Marshal(a)
+	if err != nil {
+		return otlpValue{kind: otlpString, str: fmt.Sprintf("!ERROR:%v", err)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var generic any
+
+	err = dec.Decode(&generic)
+	if err != nil {
+		return otlpValue{kind: otlpString, str: fmt.Sprintf("!ERROR:%v", err)}
+	}
+
+	return otlpGenericValue(generic)
+}
+
+// otlpGenericValue converts values produced by decoding json into an any.
+func otlpGenericValue(v any) otlpValue {
+	switch v := v.(type) {
+	case nil:
+		return otlpValue{}
+	case bool:
+		return otlpBoolValue(v)
+	case string:
+		return otlpValue{kind: otlpString, str: v}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return otlpValue{kind: otlpInt, num: i}
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return otlpValue{kind: otlpDouble, float: f}
+		}
+
+		return otlpValue{kind: otlpString, str: v.String()}
+	case []any:
+		arr := make([]otlpValue, 0, len(v))
+		for _, e := range v {
+			arr = append(arr, otlpGenericValue(e))
+		}
+
+		return otlpValue{kind: otlpArray, array: arr}
+	case map[string]any:
+		kvs := make([]otlpKeyValue, 0, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			kvs = append(kvs, otlpKeyValue{key: k, value: otlpGenericValue(v[k])})
+		}
+
+		return otlpValue{kind: otlpKVList, kvs: kvs}
+	default:
+		return otlpValue{kind: otlpString, str: fmt.Sprint(v)}
+	}
+}
+
+// OTLP/JSON encoding.  Field names are lowerCamelCase, 64-bit integers are
+// encoded as strings, and bytes are base64 encoded, following the protobuf
+// JSON mapping.
+
+func (r *otlpLogRecord) appendJSON(buf *bytes.Buffer) {
+	buf.WriteString(`{"timeUnixNano":"`)
+	buf.WriteString(strconv.FormatUint(r.timeUnixNano, 10))
+	buf.WriteString(`","observedTimeUnixNano":"`)
+	buf.WriteString(strconv.FormatUint(r.observedTimeUnixNano, 10))
+	buf.WriteString(`","severityNumber":`)
+	buf.WriteString(strconv.Itoa(r.severityNumber))
+
+	if r.severityText != "" {
+		buf.WriteString(`,"severityText":`)
+		appendJSONString(buf, r.severityText)
+	}
+
+	buf.WriteString(`,"body":{"stringValue":`)
+	appendJSONString(buf, r.body)
+	buf.WriteByte('}')
+
+	if len(r.attrs) > 0 {
+		buf.WriteString(`,"attributes":`)
+		appendOTLPJSONKeyValues(buf, r.attrs)
+	}
+
+	buf.WriteByte('}')
+}
+
+func appendOTLPJSONKeyValues(buf *bytes.Buffer, kvs []otlpKeyValue) {
+	buf.WriteByte('[')
+
+	for i, kv := range kvs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(`{"key":`)
+		appendJSONString(buf, kv.key)
+		buf.WriteString(`,"value":`)
+		kv.value.appendJSON(buf)
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(']')
+}
+
+func (v *otlpValue) appendJSON(buf *bytes.Buffer) {
+	switch v.kind {
+	case otlpString:
+		buf.WriteString(`{"stringValue":`)
+		appendJSONString(buf, v.str)
+	case otlpBool:
+		buf.WriteString(`{"boolValue":`)
+		buf.WriteString(strconv.FormatBool(v.num != 0))
+	case otlpInt:
+		buf.WriteString(`{"intValue":"`)
+		buf.WriteString(strconv.FormatInt(v.num, 10))
+		buf.WriteByte('"')
+	case otlpDouble:
+		buf.WriteString(`{"doubleValue":`)
+
+		switch {
+		case math.IsNaN(v.float):
+			buf.WriteString(`"NaN"`)
+		case math.IsInf(v.float, 1):
+			buf.WriteString(`"Infinity"`)
+		case math.IsInf(v.float, -1):
+			buf.WriteString(`"-Infinity"`)
+		default:
+			appendJSONAny(buf, v.float)
+		}
+	case otlpBytes:
+		buf.WriteString(`{"bytesValue":"`)
+		buf.WriteString(base64.StdEncoding.EncodeToString(v.bytes))
+		buf.WriteByte('"')
+	case otlpArray:
+		buf.WriteString(`{"arrayValue":{"values":[`)
+
+		for i := range v.array {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			v.array[i].appendJSON(buf)
+		}
+
+		buf.WriteString(`]}`)
+	case otlpKVList:
+		buf.WriteString(`{"kvlistValue":{"values":`)
+		appendOTLPJSONKeyValues(buf, v.kvs)
+		buf.WriteByte('}')
+	default:
+		buf.WriteByte('{')
+	}
+
+	buf.WriteByte('}')
+}
+
+// OTLP/protobuf encoding.  Field numbers are from opentelemetry/proto/logs/v1/logs.proto
+// and opentelemetry/proto/common/v1/common.proto.
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoLen     = 2
+)
+
+func (r *otlpLogRecord) appendProto(b []byte) []byte {
+	b = appendProtoFixed64(b, 1, r.timeUnixNano)
+	b = appendProtoVarint(b, 2, uint64(r.severityNumber)) //nolint:gosec // severity is clamped to 1-24
+
+	if r.severityText != "" {
+		b = appendProtoString(b, 3, r.severityText)
+	}
+
+	b = appendProtoMessage(b, 5, func(b []byte) []byte {
+		return appendProtoString(b, 1, r.body)
+	})
+
+	b = appendProtoKeyValues(b, 6, r.attrs)
+
+	return appendProtoFixed64(b, 11, r.observedTimeUnixNano)
+}
+
+func appendProtoKeyValues(b []byte, field int, kvs []otlpKeyValue) []byte {
+	for _, kv := range kvs {
+		b = appendProtoMessage(b, field, func(b []byte) []byte {
+			b = appendProtoString(b, 1, kv.key)
+			return appendProtoMessage(b, 2, kv.value.appendProto)
+		})
+	}
+
+	return b
+}
+
+func (v *otlpValue) appendProto(b []byte) []byte {
+	switch v.kind {
+	case otlpString:
+		return appendProtoString(b, 1, v.str)
+	case otlpBool:
+		return appendProtoVarint(b, 2, uint64(v.num))
+	case otlpInt:
+		return appendProtoVarint(b, 3, uint64(v.num)) //nolint:gosec // two's complement, per the protobuf spec
+	case otlpDouble:
+		return appendProtoFixed64(b, 4, math.Float64bits(v.float))
+	case otlpArray:
+		return appendProtoMessage(b, 5, func(b []byte) []byte {
+			for i := range v.array {
+				b = appendProtoMessage(b, 1, v.array[i].appendProto)
+			}
+
+			return b
+		})
+	case otlpKVList:
+		return appendProtoMessage(b, 6, func(b []byte) []byte {
+			return appendProtoKeyValues(b, 1, v.kvs)
+		})
+	case otlpBytes:
+		return appendProtoBytes(b, 7, v.bytes)
+	default:
+		return b
+	}
+}
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType)) //nolint:gosec // field numbers are small constants
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendProtoTag(b, field, protoLen)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+
+	return append(b, v...)
+}
+
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = appendProtoTag(b, field, protoLen)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+
+	return append(b, s...)
+}
+
+// appendProtoMessage appends an embedded message, encoded by fn.
+func appendProtoMessage(b []byte, field int, fn func([]byte) []byte) []byte {
+	return appendProtoBytes(b, field, fn(nil))
+}
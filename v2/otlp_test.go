@@ -0,0 +1,328 @@
+package flume
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCollector is a stand-in for an OpenTelemetry collector's OTLP/HTTP logs endpoint.
+type testCollector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+
+	// statuses are returned for the first requests, then 200
+	statuses []int
+}
+
+func newTestCollector(t *testing.T, statuses ...int) *testCollector {
+	t.Helper()
+
+	c := &testCollector{statuses: statuses}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, body)
+
+		if len(c.statuses) > 0 {
+			w.WriteHeader(c.statuses[0])
+			c.statuses = c.statuses[1:]
+		}
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *testCollector) received() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bodies
+}
+
+func TestOTLPExporter_json(t *testing.T) {
+	c := newTestCollector(t)
+
+	exp := NewOTLPExporter(&OTLPOptions{
+		Endpoint: c.URL,
+		Encoding: OTLPJSON,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+		Resource: []slog.Attr{slog.String("service.name", "myapp")},
+	})
+
+	l := slog.New(NewHandler(nil, &HandlerOptions{HandlerFn: exp.HandlerFn(), Level: slog.LevelDebug}))
+
+	fixedTime := time.Date(2024, 1, 1, 20, 41, 28, 515*1e6, time.UTC)
+
+	rec := slog.NewRecord(fixedTime, slog.LevelWarn, "hi", 0)
+	rec.Add("color", "red", "size", 5, "ok", true, "ratio", 0.5, "data", []byte("abc"),
+		"tags", []string{"a", "b"}, slog.Group("req", "method", "GET"))
+	require.NoError(t, l.Handler().WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")}).Handle(context.Background(), rec))
+
+	l.Debug("bye")
+
+	require.NoError(t, exp.Shutdown(context.Background()))
+
+	bodies := c.received()
+	require.Len(t, bodies, 1)
+
+	assert.Equal(t, "application/json", c.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", c.requests[0].Header.Get("Authorization"))
+
+	var req map[string]any
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+
+	resourceLogs := req["resourceLogs"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"attributes": []any{map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "myapp"}}},
+	}, resourceLogs["resource"])
+
+	scopeLogs := resourceLogs["scopeLogs"].([]any)
+	require.Len(t, scopeLogs, 2)
+
+	assert.Equal(t, map[string]any{"name": "http"}, scopeLogs[0].(map[string]any)["scope"])
+	assert.Equal(t, map[string]any{}, scopeLogs[1].(map[string]any)["scope"])
+
+	logRecord := scopeLogs[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	assert.NotEmpty(t, logRecord["observedTimeUnixNano"])
+	delete(logRecord, "observedTimeUnixNano")
+
+	assert.JSONEq(t, `{
+		"timeUnixNano": "1704141688515000000",
+		"severityNumber": 13,
+		"severityText": "WARN",
+		"body": {"stringValue": "hi"},
+		"attributes": [
+			{"key": "color", "value": {"stringValue": "red"}},
+			{"key": "size", "value": {"intValue": "5"}},
+			{"key": "ok", "value": {"boolValue": true}},
+			{"key": "ratio", "value": {"doubleValue": 0.5}},
+			{"key": "data", "value": {"bytesValue": "YWJj"}},
+			{"key": "tags", "value": {"arrayValue": {"values": [{"stringValue": "a"}, {"stringValue": "b"}]}}},
+			{"key": "req", "value": {"kvlistValue": {"values": [{"key": "method", "value": {"stringValue": "GET"}}]}}}
+		]
+	}`, mustMarshal(t, logRecord))
+
+	logRecord = scopeLogs[1].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	assert.InDelta(t, 5, logRecord["severityNumber"], 0)
+	assert.Equal(t, map[string]any{"stringValue": "bye"}, logRecord["body"])
+}
+
+func TestOTLPExporter_protobuf(t *testing.T) {
+	c := newTestCollector(t)
+
+	exp := NewOTLPExporter(&OTLPOptions{Endpoint: c.URL})
+
+	l := slog.New(NewHandler(nil, &HandlerOptions{HandlerFn: exp.HandlerFn()}).Named("http"))
+	l.Error("boom", "size", -5)
+
+	require.NoError(t, exp.Flush(context.Background()))
+
+	bodies := c.received()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, "application/x-protobuf", c.requests[0].Header.Get("Content-Type"))
+
+	req := decodeProto(t, bodies[0])
+	resourceLogs := decodeProto(t, req[1][0].([]byte))
+
+	resource := decodeProto(t, resourceLogs[1][0].([]byte))
+	kv := decodeProto(t, resource[1][0].([]byte))
+	assert.Equal(t, "service.name", string(kv[1][0].([]byte)))
+
+	scopeLogs := decodeProto(t, resourceLogs[2][0].([]byte))
+	scope := decodeProto(t, scopeLogs[1][0].([]byte))
+	assert.Equal(t, "http", string(scope[1][0].([]byte)))
+
+	logRecord := decodeProto(t, scopeLogs[2][0].([]byte))
+	assert.NotZero(t, logRecord[1][0], "time")
+	assert.Equal(t, uint64(17), logRecord[2][0], "severity number")
+	assert.Equal(t, "ERROR", string(logRecord[3][0].([]byte)), "severity text")
+	assert.NotZero(t, logRecord[11][0], "observed time")
+
+	body := decodeProto(t, logRecord[5][0].([]byte))
+	assert.Equal(t, "boom", string(body[1][0].([]byte)))
+
+	kv = decodeProto(t, logRecord[6][0].([]byte))
+	assert.Equal(t, "size", string(kv[1][0].([]byte)))
+
+	value := decodeProto(t, kv[2][0].([]byte))
+	assert.Equal(t, int64(-5), int64(value[3][0].(uint64))) //nolint:gosec
+}
+
+func TestOTLPExporter_batching(t *testing.T) {
+	c := newTestCollector(t)
+
+	exp := NewOTLPExporter(&OTLPOptions{Endpoint: c.URL, Encoding: OTLPJSON, BatchSize: 2, FlushInterval: time.Hour})
+	l := slog.New(NewOTLPHandler(exp, nil))
+
+	// a full batch is exported right away
+	l.Info("one")
+	l.Info("two")
+
+	require.Eventually(t, func() bool { return len(c.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	l.Info("three")
+
+	require.NoError(t, exp.Shutdown(context.Background()))
+
+	bodies := c.received()
+	require.Len(t, bodies, 2)
+	assert.Equal(t, 2, countOTLPRecords(t, bodies[0]))
+	assert.Equal(t, 1, countOTLPRecords(t, bodies[1]))
+
+	assert.ErrorIs(t, NewOTLPHandler(exp, nil).Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "four", 0)), ErrOTLPExporterClosed)
+}
+
+func TestOTLPExporter_flushInterval(t *testing.T) {
+	c := newTestCollector(t)
+
+	exp := NewOTLPExporter(&OTLPOptions{Endpoint: c.URL, Encoding: OTLPJSON, FlushInterval: 10 * time.Millisecond})
+	defer exp.Shutdown(context.Background())
+
+	slog.New(NewOTLPHandler(exp, nil)).Info("hi")
+
+	require.Eventually(t, func() bool { return len(c.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOTLPExporter_retry(t *testing.T) {
+	c := newTestCollector(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	exp := NewOTLPExporter(&OTLPOptions{Endpoint: c.URL, Encoding: OTLPJSON, RetryBackoff: time.Millisecond})
+	slog.New(NewOTLPHandler(exp, nil)).Info("hi")
+
+	require.NoError(t, exp.Flush(context.Background()))
+	assert.Len(t, c.received(), 3)
+}
+
+func TestOTLPExporter_errors(t *testing.T) {
+	c := newTestCollector(t, http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	exp := NewOTLPExporter(&OTLPOptions{Endpoint: c.URL, Encoding: OTLPJSON, MaxRetries: -1, MaxQueueSize: 1})
+	h := NewOTLPHandler(exp, nil)
+
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "one", 0)))
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "two", 0))
+	require.ErrorIs(t, err, ErrOTLPQueueFull)
+	require.ErrorIs(t, err, ErrDropped)
+
+	// not retryable
+	err = exp.Flush(context.Background())
+	require.ErrorIs(t, err, ErrOTLPExport)
+	assert.Contains(t, err.Error(), "400 Bad Request")
+
+	// retries disabled
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "three", 0)))
+	require.ErrorIs(t, exp.Flush(context.Background()), ErrOTLPExport)
+
+	assert.Len(t, c.received(), 2)
+}
+
+func TestOTLPSeverityNumber(t *testing.T) {
+	tests := map[slog.Level]int{
+		slog.LevelDebug - 10: 1,
+		slog.LevelDebug:      5,
+		slog.LevelInfo:       9,
+		slog.LevelInfo + 1:   10,
+		slog.LevelWarn:       13,
+		slog.LevelError:      17,
+		slog.LevelError + 4:  21,
+		slog.LevelError + 20: 24,
+	}
+
+	for level, want := range tests {
+		assert.Equal(t, want, otlpSeverityNumber(level), level.String())
+	}
+}
+
+func countOTLPRecords(t *testing.T, body []byte) int {
+	t.Helper()
+
+	var req struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []any `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+
+	require.NoError(t, json.Unmarshal(body, &req))
+
+	var n int
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			n += len(sl.LogRecords)
+		}
+	}
+
+	return n
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+// decodeProto decodes a protobuf message into a map of field numbers to values.  Varint
+// and fixed64 fields are decoded as uint64, and length-delimited fields as []byte.
+func decodeProto(t *testing.T, b []byte) map[int][]any {
+	t.Helper()
+
+	fields := map[int][]any{}
+
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		require.Positive(t, n, "invalid tag")
+
+		b = b[n:]
+		field := int(tag >> 3) //nolint:gosec
+
+		switch tag & 7 {
+		case protoVarint:
+			v, n := binary.Uvarint(b)
+			require.Positive(t, n, "invalid varint")
+
+			b = b[n:]
+			fields[field] = append(fields[field], v)
+		case protoFixed64:
+			require.GreaterOrEqual(t, len(b), 8)
+
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case protoLen:
+			l, n := binary.Uvarint(b)
+			require.Positive(t, n, "invalid length")
+
+			b = b[n:]
+			require.GreaterOrEqual(t, uint64(len(b)), l)
+
+			fields[field] = append(fields[field], b[:l])
+			b = b[l:]
+		default:
+			require.Fail(t, "unexpected wire type", tag&7)
+		}
+	}
+
+	return fields
+}
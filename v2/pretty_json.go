@@ -0,0 +1,184 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// DefaultPrettyJSONKeyOrder is the default order of the leading keys in records
+// rendered by NewPrettyJSONHandler.
+var DefaultPrettyJSONKeyOrder = []string{slog.TimeKey, slog.LevelKey, LoggerKey, slog.MessageKey}
+
+// PrettyJSONOptions configures NewPrettyJSONHandler.
+type PrettyJSONOptions struct {
+	// Level, AddSource, and ReplaceAttr work the same as
+	// in the slog handlers.
+	slog.HandlerOptions
+
+	// Indent is the indentation string used for each nesting level.
+	// Defaults to two spaces.
+	Indent string
+
+	// KeyOrder lists the top-level keys which should be rendered first, in
+	// that order, if present.  Defaults to DefaultPrettyJSONKeyOrder.
+	KeyOrder []string
+
+	// If SortAttrs is true, the remaining keys, including the keys of nested
+	// groups, are sorted.  Otherwise, they are rendered in the order they were
+	// added to the record.
+	SortAttrs bool
+}
+
+// NewPrettyJSONHandler returns a slog.Handler which renders each record as an indented,
+// multi-line JSON object.  It is intended for local development, where deeply
+// nested groups are hard to read on a single line.  opts may be nil.
+//
+// Unlike slog.JSONHandler, the output never contains duplicate keys: if a key
+// is added more than once to the same object, the last value wins, but the key
+// keeps its original position.  Durations are rendered as strings, like "1.5s".
+//
+// The "json-pretty" handler uses the default options.  To register a handler
+// with different options:
+//
+//	flume.RegisterHandlerFn(flume.PrettyJSONHandler, func(_ string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+//		prettyOpts := &flume.PrettyJSONOptions{SortAttrs: true}
+//		if opts != nil {
+//			prettyOpts.HandlerOptions = *opts
+//		}
+//		return flume.NewPrettyJSONHandler(w, prettyOpts)
+//	})
+func NewPrettyJSONHandler(w io.Writer, opts *PrettyJSONOptions) slog.Handler {
+	var o PrettyJSONOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Indent == "" {
+		o.Indent = "  "
+	}
+
+	if o.KeyOrder == nil {
+		o.KeyOrder = DefaultPrettyJSONKeyOrder
+	}
+
+	return newObjectHandler(w, &o.HandlerOptions, func(buf *bytes.Buffer, _ slog.Record, obj *attrObject) error {
+		var compact bytes.Buffer
+
+		encodeJSONObject(&compact, obj, o.KeyOrder, o.SortAttrs)
+
+		err := json.Indent(buf, compact.Bytes(), "", o.Indent)
+		if err != nil {
+			return fmt.Errorf("indenting json: %w", err)
+		}
+
+		buf.WriteByte('\n')
+
+		return nil
+	})
+}
+
+func encodeJSONObject(buf *bytes.Buffer, o *attrObject, keyOrder []string, sortKeys bool) {
+	keys := o.keys
+	if sortKeys {
+		keys = slices.Clone(keys)
+		slices.Sort(keys)
+	}
+
+	if len(keyOrder) > 0 {
+		ordered := make([]string, 0, len(keys))
+
+		for _, k := range keyOrder {
+			if _, ok := o.vals[k]; ok && !slices.Contains(ordered, k) {
+				ordered = append(ordered, k)
+			}
+		}
+
+		for _, k := range keys {
+			if !slices.Contains(ordered, k) {
+				ordered = append(ordered, k)
+			}
+		}
+
+		keys = ordered
+	}
+
+	buf.WriteByte('{')
+
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		appendJSONString(buf, k)
+		buf.WriteByte(':')
+
+		switch v := o.vals[k].(type) {
+		case *attrObject:
+			encodeJSONObject(buf, v, nil, sortKeys)
+		case slog.Value:
+			appendJSONValue(buf, v)
+		}
+	}
+
+	buf.WriteByte('}')
+}
+
+func appendJSONString(buf *bytes.Buffer, s string) {
+	appendJSONAny(buf, s)
+}
+
+func appendJSONValue(buf *bytes.Buffer, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		appendJSONString(buf, v.String())
+	case slog.KindInt64:
+		buf.WriteString(strconv.FormatInt(v.Int64(), 10))
+	case slog.KindUint64:
+		buf.WriteString(strconv.FormatUint(v.Uint64(), 10))
+	case slog.KindFloat64:
+		appendJSONAny(buf, v.Float64())
+	case slog.KindBool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+	case slog.KindDuration:
+		appendJSONString(buf, v.Duration().String())
+	case slog.KindTime:
+		appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+	default:
+		a := v.Any()
+		if lvl, ok := a.(slog.Level); ok {
+			appendJSONString(buf, lvl.String())
+			return
+		}
+
+		if err, ok := a.(error); ok {
+			if _, ok := err.(json.Marshaler); !ok {
+				appendJSONString(buf, err.Error())
+				return
+			}
+		}
+
+		appendJSONAny(buf, a)
+	}
+}
+
+func appendJSONAny(buf *bytes.Buffer, v any) {
+	var b bytes.Buffer
+
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(v)
+	if err != nil {
+		// fall back on rendering the value as a string
+		appendJSONString(buf, fmt.Sprintf("!ERROR:%v", err))
+		return
+	}
+
+	buf.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,187 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyJSONHandler(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 20, 41, 28, 515*1e6, time.UTC)
+
+	tests := []struct {
+		name    string
+		opts    *PrettyJSONOptions
+		handler func(h slog.Handler) slog.Handler
+		attrs   []any
+		want    string
+	}{
+		{
+			name: "default key order",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("color", "red"), slog.String(LoggerKey, "main")})
+			},
+			attrs: []any{"size", 5},
+			want: `{
+  "time": "2024-01-01T20:41:28.515Z",
+  "level": "INFO",
+  "logger": "main",
+  "msg": "hi",
+  "color": "red",
+  "size": 5
+}
+`,
+		},
+		{
+			name: "nested groups",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("req").WithAttrs([]slog.Attr{slog.String("method", "GET")}).WithGroup("headers")
+			},
+			attrs: []any{"accept", "*/*", slog.Group("empty"), slog.Group("", "inlined", true)},
+			want: `{
+  "time": "2024-01-01T20:41:28.515Z",
+  "level": "INFO",
+  "msg": "hi",
+  "req": {
+    "method": "GET",
+    "headers": {
+      "accept": "*/*",
+      "inlined": true
+    }
+  }
+}
+`,
+		},
+		{
+			name: "empty groups elided",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("req").WithGroup("headers")
+			},
+			want: `{
+  "time": "2024-01-01T20:41:28.515Z",
+  "level": "INFO",
+  "msg": "hi"
+}
+`,
+		},
+		{
+			name: "sorted attrs and custom key order",
+			opts: &PrettyJSONOptions{
+				KeyOrder:  []string{slog.MessageKey, slog.LevelKey},
+				SortAttrs: true,
+				Indent:    "\t",
+			},
+			attrs: []any{"b", 1, "a", slog.GroupValue(slog.Int("z", 1), slog.Int("y", 2))},
+			want:  "{\n\t\"msg\": \"hi\",\n\t\"level\": \"INFO\",\n\t\"a\": {\n\t\t\"y\": 2,\n\t\t\"z\": 1\n\t},\n\t\"b\": 1,\n\t\"time\": \"2024-01-01T20:41:28.515Z\"\n}\n",
+		},
+		{
+			name:  "duplicate keys",
+			attrs: []any{"color", "red", "size", 1, "color", "blue"},
+			want: `{
+  "time": "2024-01-01T20:41:28.515Z",
+  "level": "INFO",
+  "msg": "hi",
+  "color": "blue",
+  "size": 1
+}
+`,
+		},
+		{
+			name: "value kinds",
+			opts: &PrettyJSONOptions{KeyOrder: []string{}},
+			attrs: []any{
+				"dur", 1500 * time.Millisecond,
+				"err", errors.New("boom"),
+				"html", "<b>",
+				"float", 1.5,
+				"uint", uint64(7),
+				"struct", struct{ A int }{A: 1},
+			},
+			want: `{
+  "time": "2024-01-01T20:41:28.515Z",
+  "level": "INFO",
+  "msg": "hi",
+  "dur": "1.5s",
+  "err": "boom",
+  "html": "<b>",
+  "float": 1.5,
+  "uint": 7,
+  "struct": {
+    "A": 1
+  }
+}
+`,
+		},
+		{
+			name: "replace attr",
+			opts: &PrettyJSONOptions{
+				HandlerOptions: slog.HandlerOptions{
+					ReplaceAttr: ChainReplaceAttrs(removeKeys(slog.TimeKey, "color"), AbbreviateLevel),
+				},
+			},
+			attrs: []any{"color", "red", slog.Group("props", "color", "blue", "size", 1)},
+			want: `{
+  "level": "INF",
+  "msg": "hi",
+  "props": {
+    "size": 1
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+
+			var h slog.Handler = NewPrettyJSONHandler(buf, tt.opts)
+			if tt.handler != nil {
+				h = tt.handler(h)
+			}
+
+			rec := slog.NewRecord(fixedTime, slog.LevelInfo, "hi", 0)
+			rec.Add(tt.attrs...)
+
+			require.NoError(t, h.Handle(context.Background(), rec))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestPrettyJSONHandler_Enabled(t *testing.T) {
+	h := NewPrettyJSONHandler(io.Discard, nil)
+	assert.True(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+
+	h = NewPrettyJSONHandler(io.Discard, &PrettyJSONOptions{HandlerOptions: slog.HandlerOptions{Level: slog.LevelDebug}})
+	assert.True(t, h.Enabled(context.Background(), slog.LevelDebug))
+}
+
+func TestPrettyJSONHandler_slogtest(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+
+	err := slogtest.TestHandler(NewPrettyJSONHandler(buf, nil), func() []map[string]any {
+		var results []map[string]any
+
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var m map[string]any
+			require.NoError(t, dec.Decode(&m))
+
+			results = append(results, m)
+		}
+
+		return results
+	})
+	require.NoError(t, err)
+}
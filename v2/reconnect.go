@@ -0,0 +1,83 @@
+package flume
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// reconnectingConn is the connection shared by SyslogConn, GELFConn, and the journald
+// handler.  It connects lazily, on the first write, and if a write fails, it reconnects
+// and retries once.
+type reconnectingConn struct {
+	network, addr string
+	// describes the peer in errors, i.e. "syslog at udp://localhost:514"
+	desc string
+	// returned by writes after close
+	errClosed error
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func newReconnectingConn(network, addr, desc string, errClosed error) *reconnectingConn {
+	return &reconnectingConn{network: network, addr: addr, desc: desc, errClosed: errClosed}
+}
+
+// write calls fn with the connection, connecting first if there isn't one.  If fn
+// fails, the connection is dropped, and fn is retried once with a new connection.
+// Zero timeouts disable the dial timeout and the write deadline.
+func (c *reconnectingConn) write(dialTimeout, writeTimeout time.Duration, fn func(conn net.Conn) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return c.errClosed
+	}
+
+	var err error
+
+	for range 2 {
+		if c.conn == nil {
+			c.conn, err = net.DialTimeout(c.network, c.addr, dialTimeout)
+			if err != nil {
+				c.conn = nil
+				continue
+			}
+		}
+
+		if writeTimeout > 0 {
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		}
+
+		err = fn(c.conn)
+		if err == nil {
+			return nil
+		}
+
+		// drop the broken connection, and try again with a new one
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	return fmt.Errorf("writing to %s: %w", c.desc, err)
+}
+
+// close closes the connection.  Subsequent writes will fail.
+func (c *reconnectingConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,109 @@
+package flume
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// RecoverOptions configures Recover.
+type RecoverOptions struct {
+	// Message is the message of the logged record.  Defaults to "recovered from panic".
+	Message string
+
+	// Level is the level of the logged record.  Defaults to LevelError.
+	Level slog.Leveler
+
+	// If Repanic is true, the handler is flushed, and the panic is resumed
+	// after it's logged.
+	Repanic bool
+}
+
+// Recover recovers from a panic, and logs it to l, with the panic value and the
+// stack of the panicking goroutine.  It must be called directly by defer:
+//
+//	go func() {
+//		defer flume.Recover(logger, nil)
+//		...
+//	}()
+//
+// The panic value is logged as the "panic" attr, and the stack as the "stack" group,
+// with the goroutine's ID in "stack.goroutine", and the frames in "stack.frames",
+// starting with the frame which panicked.  The source of the record, if enabled, is
+// also the frame which panicked.
+//
+// If l is nil, it logs to the default handler.  opts may be nil.
+func Recover(l *slog.Logger, opts *RecoverOptions) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	var o RecoverOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Message == "" {
+		o.Message = "recovered from panic"
+	}
+
+	level := LevelError
+	if o.Level != nil {
+		level = o.Level.Level()
+	}
+
+	if l == nil {
+		l = slog.New(Default())
+	}
+
+	ctx := context.Background()
+	h := l.Handler()
+
+	if h.Enabled(ctx, level) {
+		// skip Recover, and the runtime's panic frames
+		pcs := trimRuntimePCs(callers(1))
+
+		var pc uintptr
+		if len(pcs) > 0 {
+			pc = pcs[0]
+		}
+
+		record := slog.NewRecord(time.Now(), level, o.Message, pc)
+		record.AddAttrs(
+			slog.Any("panic", panicValue(v)),
+			slog.Group("stack",
+				slog.Int("goroutine", goroutineID()),
+				slog.Any("frames", StackFromPCs(pcs)),
+			),
+		)
+
+		err := h.Handle(ctx, record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flume: logging recovered panic: %v\n", err)
+		}
+	}
+
+	if o.Repanic {
+		if f, ok := h.(Flusher); ok {
+			flushCtx, cancel := context.WithTimeout(ctx, FatalFlushTimeout)
+			_ = f.Flush(flushCtx)
+
+			cancel()
+		}
+
+		panic(v)
+	}
+}
+
+// panicValue returns errors as is, so handlers can render them, and
+// other values as strings.
+func panicValue(v any) any {
+	if err, ok := v.(error); ok {
+		return err
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,122 @@
+package flume
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func panicky(l *slog.Logger, opts *RecoverOptions, v any) {
+	defer Recover(l, opts)
+
+	panic(v)
+}
+
+// removeStack removes the attrs in the "stack" group, which vary by run.
+func removeStack(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 && groups[0] == "stack" {
+		return slog.Attr{}
+	}
+
+	return a
+}
+
+func TestRecover(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true}))
+
+	require.NotPanics(t, func() {
+		panicky(l, nil, "boom")
+	})
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+
+	assert.Equal(t, "ERROR", m["level"])
+	assert.Equal(t, "recovered from panic", m["msg"])
+	assert.Equal(t, "boom", m["panic"])
+	assert.Equal(t, "github.com/ThalesGroup/flume/v2.panicky", m["source"].(map[string]any)["function"])
+
+	stack := m["stack"].(map[string]any)
+	assert.Equal(t, float64(goroutineID()), stack["goroutine"])
+
+	frames := stack["frames"].([]any)
+	require.GreaterOrEqual(t, len(frames), 2)
+
+	frame := frames[0].(map[string]any)
+	assert.Equal(t, "github.com/ThalesGroup/flume/v2.panicky", frame["function"], "first frame should be the one which panicked")
+	assert.Contains(t, frame["file"], "recover_test.go")
+	assert.Equal(t, float64(20), frame["line"])
+	assert.Contains(t, frames[1].(map[string]any)["function"], "TestRecover")
+}
+
+func TestRecover_options(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), removeStack},
+	}))
+
+	panicky(l, &RecoverOptions{Message: "worker died", Level: slog.LevelWarn}, errors.New("bad input"))
+
+	assert.Equal(t, "level=WARN msg=\"worker died\" panic=\"bad input\"\n", buf.String())
+}
+
+func TestRecover_repanic(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	bw := bufio.NewWriter(buf)
+
+	l := slog.New(NewHandler(bw, &HandlerOptions{
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey), removeStack},
+	}))
+
+	err := errors.New("boom")
+
+	require.PanicsWithError(t, "boom", func() {
+		panicky(l, &RecoverOptions{Repanic: true}, err)
+	})
+
+	// output is buffered, so it is only written if the handler was flushed
+	assert.Equal(t, "level=ERROR msg=\"recovered from panic\" panic=boom\n", buf.String())
+}
+
+func TestRecover_disabled(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{Level: LevelOff}))
+
+	require.NotPanics(t, func() {
+		panicky(l, nil, "boom")
+	})
+
+	assert.Empty(t, buf.String())
+}
+
+func TestRecover_noPanic(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, nil))
+
+	func() {
+		defer Recover(l, nil)
+	}()
+
+	assert.Empty(t, buf.String())
+}
+
+func TestCaptureStack(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	stack := CaptureStack(0)
+
+	require.NotEmpty(t, stack)
+	assert.Equal(t, StackFrame{Function: "github.com/ThalesGroup/flume/v2.TestCaptureStack", File: file, Line: line + 1}, stack[0])
+	assert.Equal(t, "github.com/ThalesGroup/flume/v2.TestCaptureStack "+file+":"+strconv.Itoa(line+1), stack[0].String())
+
+	stack = func() Stack { return CaptureStack(1) }()
+	assert.Equal(t, "github.com/ThalesGroup/flume/v2.TestCaptureStack", stack[0].Function)
+}
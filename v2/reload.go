@@ -0,0 +1,98 @@
+package flume
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOptions configures ReloadOnSignal.
+type ReloadOptions struct {
+	// EnvVars are the environment variables the options are read from, with
+	// UnmarshalEnv.  Defaults to DefaultConfigEnvVars().
+	EnvVars []string
+
+	// File, if set, is the configuration file the options are read from, with
+	// UnmarshalFile, instead of the environment variables.
+	File string
+
+	// Signals which trigger a reload.  Defaults to SIGHUP.
+	Signals []os.Signal
+
+	// OnReload, if set, is called after each reload, with the error if the options
+	// couldn't be read, or nil if they were applied.
+	OnReload func(err error)
+}
+
+// ReloadOnSignal re-reads the handler options and applies them to h each time the
+// process receives one of the signals, until ctx is done.  This lets long running
+// daemons change levels without restarting, i.e. with `kill -HUP <pid>`.
+//
+// The environment of a running process can't be changed from outside it, so reloading
+// from environment variables is only useful if the process changes them itself, with
+// os.Setenv.  Typically, options are reloaded from File.
+//
+// The options read are merged over h's options when ReloadOnSignal is called, so
+// options set in code, like Middleware, ReplaceAttrs, and ErrorPolicy, are kept.  If
+// the options can't be read, h keeps its current options.  If none of the environment
+// variables are set, h's options are reset to those it had when ReloadOnSignal was
+// called.
+//
+// The signal handler is installed before ReloadOnSignal returns, and removed when
+// ctx is done.  opts may be nil.
+func ReloadOnSignal(ctx context.Context, h *Handler, opts *ReloadOptions) {
+	if opts == nil {
+		opts = &ReloadOptions{}
+	}
+
+	signals := opts.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	base := h.HandlerOptions()
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				err := reloadHandlerOptions(h, base, opts)
+				if opts.OnReload != nil {
+					opts.OnReload(err)
+				}
+			}
+		}
+	}()
+}
+
+// reloadHandlerOptions reads the options, merges them over base, and applies them to h.
+func reloadHandlerOptions(h *Handler, base *HandlerOptions, opts *ReloadOptions) error {
+	c := base.Clone()
+
+	var err error
+
+	switch {
+	case opts.File != "":
+		err = mergeConfigFile(c, opts.File)
+	case len(opts.EnvVars) > 0:
+		err = UnmarshalEnv(c, opts.EnvVars...)
+	default:
+		err = UnmarshalEnv(c, defaultConfigEnvVars...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	h.SetHandlerOptions(c)
+
+	return nil
+}
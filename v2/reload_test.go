@@ -0,0 +1,102 @@
+package flume
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the current process on windows")
+	}
+
+	t.Setenv("LOGCONFIG", `{"level":"WRN"}`)
+
+	buf := &syncBuffer{}
+	h := NewHandler(buf, &HandlerOptions{
+		HandlerFn:    JSONHandlerFn(),
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+		Middleware: []Middleware{SimpleMiddlewareFn(func(ctx context.Context, record slog.Record, next slog.Handler) error {
+			record.AddAttrs(slog.String("app", "billing"))
+			return next.Handle(ctx, record)
+		})},
+	})
+	errs := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ReloadOnSignal(ctx, h, &ReloadOptions{
+		EnvVars:  []string{"LOGCONFIG"},
+		OnReload: func(err error) { errs <- err },
+	})
+
+	reload := func() error {
+		t.Helper()
+
+		p, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, p.Signal(syscall.SIGHUP))
+
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for reload")
+
+			return nil
+		}
+	}
+
+	require.NoError(t, reload())
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level)
+
+	slog.New(h).Warn("hi")
+	assert.JSONEq(t, `{"level":"WARN","msg":"hi","app":"billing"}`, buf.String(), "should keep the middleware set in code")
+
+	t.Setenv("LOGCONFIG", `{"level":"loud"}`)
+
+	require.ErrorContains(t, reload(), "parsing configuration from environment variable LOGCONFIG")
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level, "should keep the options on error")
+
+	t.Setenv("LOGCONFIG", `*=ERR`)
+
+	require.NoError(t, reload())
+	assert.Equal(t, slog.LevelError, h.HandlerOptions().Level)
+}
+
+func TestReloadHandlerOptions(t *testing.T) {
+	base := &HandlerOptions{Level: slog.LevelError, AddSource: true}
+	h := NewHandler(nil, base)
+
+	path := filepath.Join(t.TempDir(), "flume.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("level: WRN"), 0o600))
+
+	require.NoError(t, reloadHandlerOptions(h, base, &ReloadOptions{File: path, EnvVars: []string{"LOGCONFIG"}}))
+	assert.Equal(t, slog.LevelWarn, h.HandlerOptions().Level, "file should take precedence over env vars")
+	assert.True(t, h.HandlerOptions().AddSource, "should merge over the base options")
+
+	t.Setenv("FLUME", `{"level":"DBG"}`)
+
+	require.NoError(t, reloadHandlerOptions(h, base, &ReloadOptions{}))
+	assert.Equal(t, slog.LevelDebug, h.HandlerOptions().Level, "should default to DefaultConfigEnvVars")
+
+	t.Setenv("FLUME", `http=DBG`)
+
+	require.NoError(t, reloadHandlerOptions(h, base, &ReloadOptions{}))
+	assert.Equal(t, slog.LevelError, h.HandlerOptions().Level, "a levels string without * should keep the base level")
+	assert.Equal(t, Levels{"http": slog.LevelDebug}, h.HandlerOptions().Levels)
+
+	require.ErrorIs(t, reloadHandlerOptions(h, base, &ReloadOptions{File: path + ".missing"}), os.ErrNotExist)
+	assert.Equal(t, slog.LevelError, h.HandlerOptions().Level)
+	assert.Equal(t, slog.LevelError, base.Level, "the base options should not be modified")
+}
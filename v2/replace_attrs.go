@@ -17,6 +17,8 @@ import (
 //   - "WARN" becomes "WRN"
 //   - "ERROR" becomes "ERR"
 //
+// Custom levels are abbreviated with the abbreviation passed to RegisterLevel, i.e. "FATAL" becomes "FTL".
+//
 // If the attribute's value is not a slog.Level, it is returned unchanged.
 //
 // Example:
@@ -34,19 +36,25 @@ func AbbreviateLevel(_ []string, attr slog.Attr) slog.Attr {
 	}
 
 	if lvl, ok := attr.Value.Any().(slog.Level); ok {
-		strLvl := lvl.String()
-		switch strLvl[0] {
-		case 'D':
-			strLvl = dbgAbbrev + strLvl[5:]
-		case 'W':
-			strLvl = wrnAbbrev + strLvl[4:]
-		case 'E':
-			strLvl = errAbbrev + strLvl[5:]
-		case 'I':
-			strLvl = infAbbrev + strLvl[4:]
-		}
+		attr.Value = slog.StringValue(formatLevel(lvl, true))
+	}
+
+	return attr
+}
+
+// NameLevel is a ReplaceAttr function that renders log levels with their registered
+// names.  slog's handlers render levels relative to slog's four built-in levels, so
+// custom levels are rendered as offsets, i.e. LevelFatal is rendered as "ERROR+4".  With
+// NameLevel, it is rendered as "FATAL".  See RegisterLevel.
+//
+// If the attribute's value is not a slog.Level, it is returned unchanged.
+func NameLevel(_ []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
 
-		attr.Value = slog.StringValue(strLvl)
+	if lvl, ok := attr.Value.Any().(slog.Level); ok {
+		attr.Value = slog.StringValue(formatLevel(lvl, false))
 	}
 
 	return attr
@@ -196,6 +204,9 @@ func (e detailedJSONError) MarshalJSON() ([]byte, error) {
 // *do not* already implement json.Marshaler, then the value is wrapped in
 // an error implementation which implements json.Marshaler, and marshals
 // the error to a string using fmt.Sprintf("%+v", err).
+//
+// To render errors as structured groups, consistently across all handlers,
+// see StructuredErrors.
 func DetailedErrors(_ []string, a slog.Attr) slog.Attr {
 	if a.Value.Kind() != slog.KindAny {
 		return a
@@ -39,6 +39,16 @@ func TestAbbreviateLevel(t *testing.T) {
 			input:    slog.Any(slog.LevelKey, slog.LevelWarn),
 			expected: slog.String(slog.LevelKey, "WRN"),
 		},
+		{
+			name:     "custom level",
+			input:    slog.Any(slog.LevelKey, LevelFatal),
+			expected: slog.String(slog.LevelKey, "FTL"),
+		},
+		{
+			name:     "custom level offset",
+			input:    slog.Any(slog.LevelKey, LevelNotice+1),
+			expected: slog.String(slog.LevelKey, "INF+3"),
+		},
 		{
 			name:     "non-level attr",
 			input:    slog.Any("foo", "bar"),
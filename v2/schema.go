@@ -0,0 +1,351 @@
+package flume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+//go:generate go run ./internal/genschema flume.schema.json
+
+// UnmarshalJSONStrict is like UnmarshalJSON, but validates the whole config first, and
+// returns all the problems it finds, joined with errors.Join, instead of only the first:
+//
+//   - unknown properties, with a suggestion if they look like a misspelling, i.e.
+//     "unknown property 'levle' (did you mean 'level'?)"
+//   - values of the wrong type
+//   - invalid levels, including each invalid level in "levels"
+//   - unregistered handlers
+//   - invalid placeholders in attrs
+//
+// Each error is prefixed with the path of the property, like "limits.maxAttrs".  If the
+// config is invalid, o is not modified.
+//
+// Like encoding/json, property names are matched case-insensitively.
+func (o *HandlerOptions) UnmarshalJSONStrict(data []byte) error {
+	err := validateConfig(data)
+	if err != nil {
+		return err
+	}
+
+	return o.UnmarshalJSON(data)
+}
+
+func validateConfig(data []byte) error {
+	var raw map[string]json.RawMessage
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return fmt.Errorf("invalid json config: %w", err)
+	}
+
+	return errors.Join(validateProperties("", raw, reflect.TypeFor[jsonHandlerOptions]())...)
+}
+
+// validateProperties validates the properties of a json object, which is unmarshaled into t.
+func validateProperties(path string, raw map[string]json.RawMessage, t reflect.Type) []error {
+	var errs []error
+
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		f, ok := lookupJSONField(t, key)
+		if !ok {
+			err := fmt.Errorf("%w '%v'", ErrUnknownProperty, path+key)
+			if suggestion := suggestProperty(t, key); suggestion != "" {
+				err = fmt.Errorf("%w (did you mean '%v'?)", err, path+suggestion)
+			}
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if nt := indirectType(f.Type); nt.Kind() == reflect.Struct {
+			// validate each property of nested objects, so errors have the full path
+			var nested map[string]json.RawMessage
+
+			err := json.Unmarshal(raw[key], &nested)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", path+key, err))
+
+				continue
+			}
+
+			errs = append(errs, validateProperties(path+key+".", nested, nt)...)
+
+			continue
+		}
+
+		v := reflect.New(f.Type)
+
+		err := json.Unmarshal(raw[key], v.Interface())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", path+key, err))
+
+			continue
+		}
+
+		for _, err := range validateValue(f, v.Elem().Interface()) {
+			errs = append(errs, fmt.Errorf("%v: %w", path+key, err))
+		}
+	}
+
+	return errs
+}
+
+func validateValue(f reflect.StructField, v any) []error {
+	if enum := f.Tag.Get("enum"); enum != "" {
+		if s, _ := v.(string); s != "" && !slices.Contains(strings.Split(enum, ","), s) {
+			return []error{fmt.Errorf("invalid value '%v': must be one of %v", s, enum)}
+		}
+	}
+
+	switch f.Tag.Get("schema") {
+	case "level":
+		if v != nil {
+			if _, err := parseLevel(v); err != nil {
+				return []error{err}
+			}
+		}
+	case "levels":
+		return validateLevels(v)
+	case "handler":
+		if s, _ := v.(string); s != "" && LookupHandlerFn(s) == nil && !isV1Encoding(f, s) {
+			return []error{fmt.Errorf("%w: '%v'", ErrUnregisteredHandler, s)}
+		}
+	case "attrs":
+		if m, _ := v.(map[string]any); m != nil {
+			if _, err := parseStaticAttrs(m); err != nil {
+				return []error{err}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateLevels(v any) []error {
+	switch lvls := v.(type) {
+	case nil:
+	case string:
+		if _, err := parseLevels(lvls); err != nil {
+			return []error{err}
+		}
+	case map[string]any:
+		var errs []error
+
+		for _, name := range slices.Sorted(maps.Keys(lvls)) {
+			if _, err := parseLevel(lvls[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", name, err))
+			}
+		}
+
+		return errs
+	default:
+		return []error{fmt.Errorf("%w '%v': must be a levels string or map", ErrInvalidLevels, v)}
+	}
+
+	return nil
+}
+
+// isV1Encoding reports whether s is one of the v1 values of the "encoding" property,
+// which are aliases for handlers.
+func isV1Encoding(f reflect.StructField, s string) bool {
+	return jsonName(f) == "encoding" && (s == "ltsv" || s == "console")
+}
+
+// lookupJSONField finds the field of struct t which encoding/json would unmarshal key into.
+func lookupJSONField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var folded *reflect.StructField
+
+	for _, f := range jsonFields(t) {
+		name := jsonName(f)
+		if name == key {
+			return f, true
+		}
+
+		if folded == nil && strings.EqualFold(name, key) {
+			folded = &f
+		}
+	}
+
+	if folded != nil {
+		return *folded, true
+	}
+
+	return reflect.StructField{}, false
+}
+
+// suggestProperty returns the property of t closest to key, or "" if none are close.
+func suggestProperty(t reflect.Type, key string) string {
+	best, bestDistance := "", len(key)/3+2
+
+	for _, f := range jsonFields(t) {
+		name := jsonName(f)
+
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// jsonFields returns the fields of struct t which are unmarshaled from json.
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.IsExported() && jsonName(f) != "-" {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// JSONSchema returns a JSON Schema for the json config, which editors can use to
+// validate and complete configuration files, including yaml files.  The schema is
+// generated from the properties UnmarshalJSON supports.  The names of the handlers
+// registered when it is called are listed as examples of the "handler" property.
+//
+// The schema of the built-in handlers is published in flume.schema.json, in the root
+// of the module.
+func JSONSchema() []byte {
+	schema := objectSchema(reflect.TypeFor[jsonHandlerOptions]())
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "flume configuration"
+	schema["$defs"] = map[string]any{
+		"level": map[string]any{
+			"description": `a level name or abbreviation, with an optional offset, like "INF" or "WRN+1", ` +
+				`an integer, "ALL", "OFF", or a bool, where true is ALL and false is OFF`,
+			"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "integer"},
+				map[string]any{"type": "boolean"},
+			},
+			"examples": []any{"DBG", "INF", "WRN", "ERR", "INF+1", "ALL", "OFF"},
+		},
+		"levels": map[string]any{
+			"anyOf": []any{
+				map[string]any{
+					"description": `a levels string, like "*=INF,http=DBG,-sql"`,
+					"type":        "string",
+				},
+				map[string]any{
+					"description":          "an object mapping logger names to levels",
+					"type":                 "object",
+					"additionalProperties": map[string]any{"$ref": "#/$defs/level"},
+				},
+			},
+		},
+		"handler": map[string]any{
+			"type":     "string",
+			"examples": handlerNames(),
+		},
+	}
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		// the schema is built from maps, slices, and strings, which always marshal
+		panic(err)
+	}
+
+	return append(b, '\n')
+}
+
+func objectSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+
+	for _, f := range jsonFields(t) {
+		props[jsonName(f)] = propertySchema(f)
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func propertySchema(f reflect.StructField) map[string]any {
+	var schema map[string]any
+
+	t := indirectType(f.Type)
+
+	switch {
+	case f.Tag.Get("schema") == "attrs":
+		schema = map[string]any{
+			"type":                 "object",
+			"additionalProperties": true,
+		}
+	case f.Tag.Get("schema") != "":
+		schema = map[string]any{"$ref": "#/$defs/" + f.Tag.Get("schema")}
+	case t.Kind() == reflect.Struct:
+		schema = objectSchema(t)
+	case t.Kind() == reflect.Bool:
+		schema = map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.Int:
+		schema = map[string]any{"type": "integer"}
+	default:
+		schema = map[string]any{"type": "string"}
+	}
+
+	if enum := f.Tag.Get("enum"); enum != "" {
+		schema["enum"] = strings.Split(enum, ",")
+	}
+
+	if doc := f.Tag.Get("doc"); doc != "" {
+		schema["description"] = doc
+	}
+
+	return schema
+}
@@ -0,0 +1,175 @@
+package flume
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerOptions_UnmarshalJSONStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected HandlerOptions
+		want     []string
+	}{
+		{
+			name:     "valid",
+			json:     `{"level":"WRN","levels":{"http":"DBG"},"handler":"json","limits":{"maxAttrs":3},"errorFallback":"stderr"}`,
+			expected: HandlerOptions{Level: LevelWarn, Levels: Levels{"http": LevelDebug}, HandlerFn: JSONHandlerFn(), Limits: &Limits{MaxAttrs: 3}},
+		},
+		{
+			name:     "case-insensitive properties",
+			json:     `{"Level":"WRN","AddSource":true}`,
+			expected: HandlerOptions{Level: LevelWarn, AddSource: true},
+		},
+		{
+			name:     "v1 encodings",
+			json:     `{"encoding":"ltsv"}`,
+			expected: HandlerOptions{HandlerFn: TextHandlerFn()},
+		},
+		{
+			name: "unknown properties",
+			json: `{"levle":"WRN","adSource":true,"colour":"red","limits":{"maxAtrs":3}}`,
+			want: []string{
+				"unknown property 'adSource' (did you mean 'addSource'?)",
+				"unknown property 'colour'",
+				"unknown property 'levle' (did you mean 'level'?)",
+				"unknown property 'limits.maxAtrs' (did you mean 'limits.maxAttrs'?)",
+			},
+		},
+		{
+			name: "all invalid levels",
+			json: `{"level":"loud","addStacktrace":"quiet","levels":{"http":"DBG","sql":"lots","authz":"none"}}`,
+			want: []string{
+				"addStacktrace: invalid log level 'quiet'",
+				"level: invalid log level 'loud'",
+				"levels: authz: invalid log level 'none'",
+				"levels: sql: invalid log level 'lots'",
+			},
+		},
+		{
+			name: "levels string",
+			json: `{"levels":"http=loud,sql=lots"}`,
+			want: []string{
+				"levels: invalid levels value 'http=loud,sql=lots': invalid log level 'loud'",
+				"invalid log level 'lots'",
+			},
+		},
+		{
+			name: "handlers and types",
+			json: `{"handler":"xml","encoding":"yaml","addSource":"yes","stacktraceSkip":1.5,"trimSource":{"pathSegments":"two"},"errorFallback":"syslog"}`,
+			want: []string{
+				"addSource: json: cannot unmarshal string into Go value of type bool",
+				"encoding: unregistered handler: 'yaml'",
+				"errorFallback: invalid value 'syslog': must be one of stderr,stdout",
+				"handler: unregistered handler: 'xml'",
+				"stacktraceSkip: json: cannot unmarshal number 1.5 into Go value of type int",
+				"trimSource.pathSegments: json: cannot unmarshal string into Go value of type int",
+			},
+		},
+		{
+			name: "placeholders",
+			json: `{"attrs":{"host":"${hostnme}"},"fields":{"pid":"${pdi}"}}`,
+			want: []string{
+				`attrs: attr "host": invalid placeholder: unknown placeholder ${hostnme}`,
+				`fields: attr "pid": invalid placeholder: unknown placeholder ${pdi}`,
+			},
+		},
+		{
+			name: "not an object",
+			json: `[]`,
+			want: []string{"invalid json config: json: cannot unmarshal array"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := HandlerOptions{}
+
+			err := opts.UnmarshalJSONStrict([]byte(test.json))
+			if len(test.want) == 0 {
+				require.NoError(t, err)
+				assertHandlerOptionsEqual(t, test.expected, opts, "")
+
+				// strict mode should agree with UnmarshalJSON
+				var lenient HandlerOptions
+
+				require.NoError(t, json.Unmarshal([]byte(test.json), &lenient))
+				assertHandlerOptionsEqual(t, test.expected, lenient, "")
+
+				return
+			}
+
+			require.Error(t, err)
+			assertHandlerOptionsEqual(t, HandlerOptions{}, opts, "")
+
+			msg := err.Error()
+			for _, want := range test.want {
+				assert.Contains(t, msg, want)
+			}
+
+			if !strings.HasPrefix(msg, "invalid json config") {
+				// one line per problem, though level errors may include more detail
+				assert.Len(t, strings.Split(msg, "\n"), len(test.want))
+			}
+		})
+	}
+}
+
+func TestHandlerOptions_UnmarshalJSONStrict_errorsIs(t *testing.T) {
+	var opts HandlerOptions
+
+	err := opts.UnmarshalJSONStrict([]byte(`{"levle":"INF","level":"loud","handler":"xml"}`))
+	require.ErrorIs(t, err, ErrUnknownProperty)
+	require.ErrorIs(t, err, ErrInvalidLevel)
+	require.ErrorIs(t, err, ErrUnregisteredHandler)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("level", "level"))
+	assert.Equal(t, 2, editDistance("levle", "level"))
+	assert.Equal(t, 1, editDistance("adsource", "addsource"))
+	assert.Equal(t, 5, editDistance("", "level"))
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]any
+
+	require.NoError(t, json.Unmarshal(JSONSchema(), &schema))
+
+	props, ok := schema["properties"].(map[string]any)
+	require.True(t, ok)
+
+	// every property UnmarshalJSON accepts is in the schema
+	for _, f := range jsonFields(reflect.TypeFor[jsonHandlerOptions]()) {
+		assert.Contains(t, props, jsonName(f))
+	}
+
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/level", "description": "the default level for all loggers"}, props["level"])
+	assert.Equal(t, map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"description":          "limits the size of records, see LimitsMiddleware",
+		"properties": map[string]any{
+			"maxMessageLength": map[string]any{"type": "integer"},
+			"maxStringLength":  map[string]any{"type": "integer"},
+			"maxAttrs":         map[string]any{"type": "integer"},
+			"maxGroupDepth":    map[string]any{"type": "integer"},
+			"marker":           map[string]any{"type": "string"},
+		},
+	}, props["limits"])
+	assert.Equal(t, []any{"stderr", "stdout"}, props["errorFallback"].(map[string]any)["enum"]) //nolint:forcetypeassert
+}
+
+func TestJSONSchema_published(t *testing.T) {
+	published, err := os.ReadFile("flume.schema.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(JSONSchema()), string(published), "flume.schema.json is out of date: run go generate")
+}
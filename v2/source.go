@@ -0,0 +1,147 @@
+package flume
+
+import (
+	"log/slog"
+	"path"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// SourceOptions configures how TrimSource rewrites source locations.
+type SourceOptions struct {
+	// ModuleRelative rewrites file paths to the import path of the file's package,
+	// followed by the file name, like "github.com/ThalesGroup/flume/v2/handler.go".  Files
+	// in the main module are made relative to the main module's path, like
+	// "internal/server/server.go".  The module path is read from the binary's build info.
+	ModuleRelative bool `json:"moduleRelative,omitempty"`
+
+	// PathSegments, if greater than zero, keeps only the last PathSegments segments of
+	// file paths, like the term handler does.  If ModuleRelative is also set, the segments
+	// are trimmed from the module relative path.
+	PathSegments int `json:"pathSegments,omitempty"`
+
+	// ShortFunction collapses function names to the package name and the function, i.e.
+	// "github.com/ThalesGroup/flume/v2.(*Handler).Handle" becomes "v2.(*Handler).Handle".
+	ShortFunction bool `json:"shortFunction,omitempty"`
+}
+
+// TrimSource returns a ReplaceAttr function which shortens the file paths and function
+// names of *slog.Source values, according to opts.  By default, the JSON and text handlers
+// render source file paths as absolute paths on the build machine.
+//
+// Example:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+//		AddSource:   true,
+//		ReplaceAttr: TrimSource(SourceOptions{ModuleRelative: true}),
+//	}))
+//
+//	logger.Info("hi")
+//	// Output might be:
+//	// {"time":"...","level":"INFO","source":{"function":"main.main","file":"cmd/server/main.go","line":12},"msg":"hi"}
+func TrimSource(opts SourceOptions) func([]string, slog.Attr) slog.Attr {
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindAny {
+			return a
+		}
+
+		src, ok := a.Value.Any().(*slog.Source)
+		if !ok || src == nil {
+			return a
+		}
+
+		// the source may be shared, so modify a copy
+		trimmed := *src
+
+		if opts.ModuleRelative {
+			trimmed.File = moduleRelativePath(trimmed.Function, trimmed.File)
+		}
+
+		if opts.PathSegments > 0 {
+			trimmed.File = lastPathSegments(trimmed.File, opts.PathSegments)
+		}
+
+		if opts.ShortFunction {
+			trimmed.Function = shortFunction(trimmed.Function)
+		}
+
+		a.Value = slog.AnyValue(&trimmed)
+
+		return a
+	}
+}
+
+var buildPaths = sync.OnceValues(func() (mainPkg, mainModule string) {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		return bi.Path, bi.Main.Path
+	}
+
+	return "", ""
+})
+
+// moduleRelativePath derives the import path of the file's package from the
+// name of the function, since the file path may be anywhere on the build machine.
+// If the package can't be determined, the file is returned unchanged.
+func moduleRelativePath(function, file string) string {
+	pkg := packagePath(function)
+
+	mainPkg, mainModule := buildPaths()
+
+	if pkg == "main" {
+		pkg = mainPkg
+	}
+
+	if pkg == "" || pkg == "main" {
+		return file
+	}
+
+	file = pkg + "/" + path.Base(file)
+
+	if mainModule != "" {
+		file = strings.TrimPrefix(file, mainModule+"/")
+	}
+
+	return file
+}
+
+// packagePath returns the import path of the package of a function name
+// from runtime.Frame, like "github.com/ThalesGroup/flume/v2.(*Handler).Handle".
+func packagePath(function string) string {
+	// the package path ends at the first dot after the last slash.  Generic type
+	// arguments may contain slashes, so ignore them.
+	if i := strings.IndexByte(function, '['); i >= 0 {
+		function = function[:i]
+	}
+
+	slash := strings.LastIndexByte(function, '/') + 1
+
+	dot := strings.IndexByte(function[slash:], '.')
+	if dot < 0 {
+		return ""
+	}
+
+	return function[:slash+dot]
+}
+
+func shortFunction(function string) string {
+	name := function
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+
+	return function[strings.LastIndexByte(name, '/')+1:]
+}
+
+func lastPathSegments(file string, n int) string {
+	i := len(file)
+
+	for ; n > 0; n-- {
+		i = strings.LastIndexAny(file[:i], `/\`)
+		if i < 0 {
+			return file
+		}
+	}
+
+	return file[i+1:]
+}
@@ -0,0 +1,128 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimSource(t *testing.T) {
+	_, mainModule := buildPaths()
+	require.Equal(t, "github.com/ThalesGroup/flume/v2", mainModule)
+
+	tests := []struct {
+		name string
+		opts SourceOptions
+		src  slog.Source
+		want slog.Source
+	}{
+		{
+			name: "no options",
+			src:  slog.Source{Function: "github.com/ThalesGroup/flume/v2.New", File: "/build/flume/v2/default.go", Line: 3},
+			want: slog.Source{Function: "github.com/ThalesGroup/flume/v2.New", File: "/build/flume/v2/default.go", Line: 3},
+		},
+		{
+			name: "module relative",
+			opts: SourceOptions{ModuleRelative: true},
+			src:  slog.Source{Function: "github.com/ThalesGroup/flume/v2/flumetest.(*T).Log", File: "/build/flume/v2/flumetest/flumetest.go", Line: 3},
+			want: slog.Source{Function: "github.com/ThalesGroup/flume/v2/flumetest.(*T).Log", File: "flumetest/flumetest.go", Line: 3},
+		},
+		{
+			name: "module relative root package",
+			opts: SourceOptions{ModuleRelative: true},
+			src:  slog.Source{Function: "github.com/ThalesGroup/flume/v2.New.func1", File: "/build/flume/v2/default.go"},
+			want: slog.Source{Function: "github.com/ThalesGroup/flume/v2.New.func1", File: "default.go"},
+		},
+		{
+			name: "module relative dependency",
+			opts: SourceOptions{ModuleRelative: true},
+			src:  slog.Source{Function: "github.com/stretchr/testify/assert.Equal", File: "/root/go/pkg/mod/github.com/stretchr/testify@v1.9.0/assert/assertions.go"},
+			want: slog.Source{Function: "github.com/stretchr/testify/assert.Equal", File: "github.com/stretchr/testify/assert/assertions.go"},
+		},
+		{
+			name: "module relative generic function",
+			opts: SourceOptions{ModuleRelative: true},
+			src:  slog.Source{Function: "slices.SortFunc[go.shape.[]example.com/x.T]", File: "/usr/local/go/src/slices/sort.go"},
+			want: slog.Source{Function: "slices.SortFunc[go.shape.[]example.com/x.T]", File: "slices/sort.go"},
+		},
+		{
+			name: "module relative unknown function",
+			opts: SourceOptions{ModuleRelative: true},
+			src:  slog.Source{File: "/build/flume/v2/default.go"},
+			want: slog.Source{File: "/build/flume/v2/default.go"},
+		},
+		{
+			name: "path segments",
+			opts: SourceOptions{PathSegments: 2},
+			src:  slog.Source{File: "/build/flume/v2/flumetest/flumetest.go"},
+			want: slog.Source{File: "flumetest/flumetest.go"},
+		},
+		{
+			name: "more path segments than path",
+			opts: SourceOptions{PathSegments: 5},
+			src:  slog.Source{File: "flume/v2/default.go"},
+			want: slog.Source{File: "flume/v2/default.go"},
+		},
+		{
+			name: "windows path segments",
+			opts: SourceOptions{PathSegments: 1},
+			src:  slog.Source{File: `C:\build\flume\default.go`},
+			want: slog.Source{File: "default.go"},
+		},
+		{
+			name: "module relative and path segments",
+			opts: SourceOptions{ModuleRelative: true, PathSegments: 2},
+			src:  slog.Source{Function: "github.com/stretchr/testify/assert.Equal", File: "/root/go/pkg/mod/github.com/stretchr/testify@v1.9.0/assert/assertions.go"},
+			want: slog.Source{Function: "github.com/stretchr/testify/assert.Equal", File: "assert/assertions.go"},
+		},
+		{
+			name: "short function",
+			opts: SourceOptions{ShortFunction: true},
+			src:  slog.Source{Function: "github.com/ThalesGroup/flume/v2.(*Handler).Handle"},
+			want: slog.Source{Function: "v2.(*Handler).Handle"},
+		},
+		{
+			name: "short generic function",
+			opts: SourceOptions{ShortFunction: true},
+			src:  slog.Source{Function: "slices.SortFunc[go.shape.[]example.com/x.T]"},
+			want: slog.Source{Function: "slices.SortFunc[go.shape.[]example.com/x.T]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.src
+
+			a := TrimSource(tt.opts)(nil, slog.Any(slog.SourceKey, &src))
+
+			assert.Equal(t, slog.SourceKey, a.Key)
+			assert.Equal(t, &tt.want, a.Value.Any())
+			assert.Equal(t, tt.src, src, "the original source should not be modified")
+		})
+	}
+
+	a := TrimSource(SourceOptions{ModuleRelative: true})(nil, slog.String(slog.SourceKey, "/build/main.go:2"))
+	assert.Equal(t, slog.String(slog.SourceKey, "/build/main.go:2"), a, "only *slog.Source values should be trimmed")
+}
+
+func TestHandlerOptions_TrimSource(t *testing.T) {
+	var opts HandlerOptions
+
+	require.NoError(t, json.Unmarshal([]byte(`{"addSource":true,"trimSource":{"moduleRelative":true,"shortFunction":true}}`), &opts))
+
+	buf := bytes.NewBuffer(nil)
+	opts.HandlerFn = JSONHandlerFn()
+	opts.ReplaceAttrs = []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)}
+
+	_, _, line, _ := runtime.Caller(0)
+
+	slog.New(NewHandler(buf, &opts)).Info("hi")
+
+	assert.JSONEq(t, `{"level":"INFO","source":{"function":"v2.TestHandlerOptions_TrimSource","file":"source_test.go","line":`+strconv.Itoa(line+2)+`},"msg":"hi"}`, buf.String())
+}
@@ -0,0 +1,160 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StacktraceKey is the key of the stack trace attr added by StacktraceMiddleware.
+const StacktraceKey = "stacktrace"
+
+// StackFrame is a single frame of a stack trace.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// String renders the frame as "function file:line".
+func (f StackFrame) String() string {
+	return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// Stack is a stack trace, innermost frame first.  It renders as a list of
+// objects in JSON, and as "function file:line" lines in text.
+type Stack []StackFrame
+
+// String renders the stack with one frame per line.
+func (s Stack) String() string {
+	var sb strings.Builder
+
+	for i, f := range s {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+
+		sb.WriteString(f.String())
+	}
+
+	return sb.String()
+}
+
+// CaptureStack returns the stack of the calling goroutine.  skip is the number
+// of frames to skip: 0 is the caller of CaptureStack.
+func CaptureStack(skip int) Stack {
+	// skip CaptureStack
+	return StackFromPCs(callers(skip + 1))
+}
+
+// callers returns the program counters of the calling goroutine's stack.  skip
+// is the number of frames to skip: 0 is the caller of callers.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+
+	for {
+		// skip runtime.Callers and callers
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) {
+			return pcs[:n]
+		}
+
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+}
+
+// StackFromPCs converts program counters, like those returned by runtime.Callers,
+// to a Stack.
+func StackFromPCs(pcs []uintptr) Stack {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	stack := make(Stack, 0, len(pcs))
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, StackFrame{Function: f.Function, File: f.File, Line: f.Line})
+
+		if !more {
+			return stack
+		}
+	}
+}
+
+// trimRuntimePCs removes leading frames in the runtime package, like runtime.gopanic.
+func trimRuntimePCs(pcs []uintptr) []uintptr {
+	for len(pcs) > 0 {
+		f, _ := runtime.CallersFrames(pcs[:1]).Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			break
+		}
+
+		pcs = pcs[1:]
+	}
+
+	return pcs
+}
+
+// goroutineID parses the current goroutine's ID from the header of its stack trace,
+// i.e. "goroutine 18 [running]:".  It returns 0 if the header can't be parsed.
+func goroutineID() int {
+	var buf [64]byte
+
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		id, err := strconv.Atoi(string(b[:i]))
+		if err == nil {
+			return id
+		}
+	}
+
+	return 0
+}
+
+// StacktraceMiddleware returns middleware which adds a stack trace to records at or above
+// level, as a Stack attr named StacktraceKey.  Handlers render the Stack natively:
+// the JSON handlers render it as a list of frame objects, and the text and term handlers
+// render it as a string, with one frame per line.  It is installed by the AddStacktrace
+// and StacktraceSkip options.
+//
+// The stack trace starts at the frame which logged the record, identified by
+// record.PC, so the frames of the logger and handlers are omitted.  skip omits
+// additional frames, to remove logging wrappers from the trace.  If record.PC is
+// zero, no stack trace is added.  If record.PC is not found on the current stack,
+// e.g. because the record is being handled asynchronously, the stack trace just
+// contains the frame of record.PC.
+//
+// Like other attrs added by middleware, if the logger has open groups, the stack trace
+// is added inside the innermost group.
+func StacktraceMiddleware(level slog.Leveler, skip int) Middleware {
+	return SimpleMiddlewareFn(func(ctx context.Context, record slog.Record, next slog.Handler) error {
+		if record.PC == 0 || record.Level < level.Level() {
+			return next.Handle(ctx, record)
+		}
+
+		pcs := callers(0)
+
+		i := slices.Index(pcs, record.PC)
+		if i < 0 {
+			pcs = []uintptr{record.PC}
+		} else {
+			pcs = pcs[min(i+max(skip, 0), len(pcs)):]
+		}
+
+		if len(pcs) == 0 {
+			return next.Handle(ctx, record)
+		}
+
+		record = record.Clone()
+		record.AddAttrs(slog.Any(StacktraceKey, StackFromPCs(pcs)))
+
+		return next.Handle(ctx, record)
+	})
+}
@@ -0,0 +1,90 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logWrapper is a logging helper, whose frame should be skipped in stack traces.
+func logWrapper(l *slog.Logger, msg string) {
+	l.Error(msg)
+}
+
+func TestStacktraceMiddleware(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{
+		HandlerFn:     JSONHandlerFn(),
+		AddStacktrace: slog.LevelWarn,
+		ReplaceAttrs:  []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+	}))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	l.Error("boom")
+
+	var m struct {
+		Stacktrace Stack `json:"stacktrace"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	require.NotEmpty(t, m.Stacktrace)
+	assert.Equal(t, StackFrame{Function: "github.com/ThalesGroup/flume/v2.TestStacktraceMiddleware", File: file, Line: line + 2}, m.Stacktrace[0])
+	assert.Equal(t, "testing.tRunner", m.Stacktrace[1].Function)
+
+	buf.Reset()
+
+	l.Info("hi")
+
+	assert.JSONEq(t, `{"level":"INFO","msg":"hi"}`, buf.String(), "records below the level should not have a stack trace")
+}
+
+func TestStacktraceMiddleware_skip(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{
+		AddStacktrace:  slog.LevelError,
+		StacktraceSkip: 1,
+		ReplaceAttrs:   []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+	}))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	logWrapper(l, "boom")
+
+	prefix := "level=ERROR msg=boom stacktrace=\"github.com/ThalesGroup/flume/v2.TestStacktraceMiddleware_skip " + file + ":" + strconv.Itoa(line+2) + "\\n"
+	assert.True(t, strings.HasPrefix(buf.String(), prefix), "got %q", buf.String())
+}
+
+func TestStacktraceMiddleware_unknownPC(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	h := StacktraceMiddleware(slog.LevelInfo, 0).Apply(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeKeys(slog.TimeKey)}))
+
+	_, file, line, _ := runtime.Caller(0)
+
+	var pcs [1]uintptr
+
+	runtime.Callers(1, pcs[:])
+
+	// handled in another goroutine, so the PC is not on the stack
+	done := make(chan error)
+	go func() {
+		done <- h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", pcs[0]))
+	}()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "level=INFO msg=hi stacktrace=\"github.com/ThalesGroup/flume/v2.TestStacktraceMiddleware_unknownPC "+file+":"+strconv.Itoa(line+4)+"\"\n", buf.String())
+
+	buf.Reset()
+
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", 0)))
+	assert.Equal(t, "level=INFO msg=hi\n", buf.String(), "records without a PC should not have a stack trace")
+}
@@ -0,0 +1,132 @@
+package flume
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parseStaticAttrs converts the "attrs" section of the JSON config to attrs.  Strings
+// may contain placeholders, which are resolved immediately.  Objects are converted to
+// groups, with their keys sorted.
+func parseStaticAttrs(m map[string]any) ([]slog.Attr, error) {
+	attrs := make([]slog.Attr, 0, len(m))
+
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		v, err := staticAttrValue(m[k])
+		if err != nil {
+			return nil, fmt.Errorf("attr %q: %w", k, err)
+		}
+
+		attrs = append(attrs, slog.Attr{Key: k, Value: v})
+	}
+
+	return attrs, nil
+}
+
+func staticAttrValue(v any) (slog.Value, error) {
+	switch v := v.(type) {
+	case string:
+		s, err := expandPlaceholders(v)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.StringValue(s), nil
+	case float64:
+		if v == float64(int64(v)) {
+			return slog.Int64Value(int64(v)), nil
+		}
+
+		return slog.Float64Value(v), nil
+	case map[string]any:
+		attrs, err := parseStaticAttrs(v)
+		if err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.GroupValue(attrs...), nil
+	default:
+		// bools, nulls, and arrays
+		return slog.AnyValue(v), nil
+	}
+}
+
+// expandPlaceholders replaces placeholders in s, like "${hostname}".  See HandlerOptions.UnmarshalJSON
+// for the supported placeholders.
+func expandPlaceholders(s string) (string, error) {
+	var sb strings.Builder
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unterminated placeholder in %q", ErrInvalidPlaceholder, s)
+		}
+
+		end += start
+
+		val, err := resolvePlaceholder(s[start+2 : end])
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(s[:start])
+		sb.WriteString(val)
+
+		s = s[end+1:]
+	}
+
+	if sb.Len() == 0 {
+		return s, nil
+	}
+
+	sb.WriteString(s)
+
+	return sb.String(), nil
+}
+
+func resolvePlaceholder(name string) (string, error) {
+	if envVar, ok := strings.CutPrefix(name, "env:"); ok {
+		return os.Getenv(envVar), nil
+	}
+
+	switch name {
+	case "hostname":
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("resolving ${hostname}: %w", err)
+		}
+
+		return hostname, nil
+	case "pid":
+		return strconv.Itoa(os.Getpid()), nil
+	case "version":
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			return bi.Main.Version, nil
+		}
+
+		return "", nil
+	case "revision":
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					return setting.Value, nil
+				}
+			}
+		}
+
+		return "", nil
+	default:
+		return "", fmt.Errorf("%w: unknown placeholder ${%s}", ErrInvalidPlaceholder, name)
+	}
+}
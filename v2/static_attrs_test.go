@@ -0,0 +1,110 @@
+package flume
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPlaceholders(t *testing.T) {
+	t.Setenv("FLUME_TEST_REGION", "us-east-1")
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	bi, ok := debug.ReadBuildInfo()
+	require.True(t, ok)
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "", want: ""},
+		{in: "billing", want: "billing"},
+		{in: "${hostname}", want: hostname},
+		{in: "${pid}", want: strconv.Itoa(os.Getpid())},
+		{in: "${version}", want: bi.Main.Version},
+		{in: "${env:FLUME_TEST_REGION}", want: "us-east-1"},
+		{in: "${env:FLUME_TEST_UNSET}", want: ""},
+		{in: "${hostname}:${pid}/${env:FLUME_TEST_REGION}!", want: hostname + ":" + strconv.Itoa(os.Getpid()) + "/us-east-1!"},
+		{in: "$hostname {pid}", want: "$hostname {pid}"},
+		{in: "${user}", wantErr: "invalid placeholder: unknown placeholder ${user}"},
+		{in: "${hostname", wantErr: `invalid placeholder: unterminated placeholder in "${hostname"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := expandPlaceholders(tt.in)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, ErrInvalidPlaceholder)
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandlerOptions_Attrs(t *testing.T) {
+	t.Setenv("FLUME_TEST_REGION", "us-east-1")
+
+	var opts HandlerOptions
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"handler":"json",
+		"attrs":{
+			"service":"billing",
+			"pid":"${pid}",
+			"replicas":3,
+			"ratio":0.5,
+			"canary":true,
+			"deploy":{"region":"${env:FLUME_TEST_REGION}","zone":"b"}
+		}
+	}`), &opts))
+
+	assert.Equal(t, []slog.Attr{
+		slog.Bool("canary", true),
+		slog.Group("deploy", slog.String("region", "us-east-1"), slog.String("zone", "b")),
+		slog.String("pid", strconv.Itoa(os.Getpid())),
+		slog.Float64("ratio", 0.5),
+		slog.Int("replicas", 3),
+		slog.String("service", "billing"),
+	}, opts.Attrs)
+
+	buf := bytes.NewBuffer(nil)
+	opts.ReplaceAttrs = []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey, "pid")}
+	h := NewHandler(buf, &opts)
+
+	slog.New(h).Info("hi", "color", "red")
+	slog.New(h.Named("http")).WithGroup("req").Info("hi", "path", "/")
+
+	assert.Equal(t,
+		`{"level":"INFO","msg":"hi","canary":true,"deploy":{"region":"us-east-1","zone":"b"},"ratio":0.5,"replicas":3,"service":"billing","color":"red"}`+"\n"+
+			`{"level":"INFO","msg":"hi","canary":true,"deploy":{"region":"us-east-1","zone":"b"},"ratio":0.5,"replicas":3,"service":"billing","logger":"http","req":{"path":"/"}}`+"\n",
+		buf.String())
+}
+
+func TestHandlerOptions_Attrs_fields(t *testing.T) {
+	var opts HandlerOptions
+
+	require.NoError(t, json.Unmarshal([]byte(`{"fields":{"service":"billing"}}`), &opts))
+	assert.Equal(t, []slog.Attr{slog.String("service", "billing")}, opts.Attrs)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"fields":{"service":"billing"},"attrs":{"app":"billing"}}`), &opts))
+	assert.Equal(t, []slog.Attr{slog.String("app", "billing")}, opts.Attrs, "attrs should win over fields")
+
+	err := json.Unmarshal([]byte(`{"attrs":{"deploy":{"host":"${host}"}}}`), &opts)
+	require.ErrorIs(t, err, ErrInvalidPlaceholder)
+	assert.EqualError(t, err, `attr "deploy": attr "host": invalid placeholder: unknown placeholder ${host}`)
+}
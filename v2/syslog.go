@@ -0,0 +1,488 @@
+package flume
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogFormat selects the syslog message format.
+type SyslogFormat int
+
+const (
+	// RFC5424 is the modern syslog format, with structured data.
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the legacy BSD syslog format.  Attrs are appended to
+	// the message as key=value pairs.
+	RFC3164
+)
+
+// Syslog facilities, see RFC 5424 section 6.2.1.
+const (
+	FacilityKern   = 0
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityAuth   = 4
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// Syslog severities, see RFC 5424 section 6.2.1.
+const (
+	SeverityEmergency = 0
+	SeverityAlert     = 1
+	SeverityCritical  = 2
+	SeverityError     = 3
+	SeverityWarning   = 4
+	SeverityNotice    = 5
+	SeverityInfo      = 6
+	SeverityDebug     = 7
+)
+
+// DefaultSyslogEnterpriseID is the private enterprise number used to build
+// SD-IDs if SyslogOptions.EnterpriseID is not set.  32473 is the number reserved
+// for documentation and examples by RFC 5612.  Applications should replace it
+// with their own organization's number.
+const DefaultSyslogEnterpriseID = "32473"
+
+// SyslogOptions configures NewSyslogHandler.
+type SyslogOptions struct {
+	// Level, AddSource, and ReplaceAttr work the same as
+	// in the slog handlers.
+	slog.HandlerOptions
+
+	// Format defaults to RFC5424.
+	Format SyslogFormat
+
+	// Facility defaults to FacilityUser.  The kernel facility is reserved
+	// for kernel messages, and can't be selected.
+	Facility int
+
+	// Hostname defaults to os.Hostname().
+	Hostname string
+
+	// AppName is the APP-NAME (or TAG, in RFC 3164) of messages which don't have
+	// a LoggerKey attr, or all messages if LoggerAsMsgID is true.  Defaults to the
+	// base name of the program.
+	AppName string
+
+	// By default, the value of the LoggerKey attr is used as the APP-NAME.  If
+	// LoggerAsMsgID is true, it is used as the MSGID instead.
+	LoggerAsMsgID bool
+
+	// SDID is the name of the SD-ID of the structured data element which holds
+	// the top-level attrs.  Defaults to "flume".  Each top-level group is rendered
+	// as a separate element, named after the group.  Nested groups are flattened
+	// into the parameter names, joined by ".".
+	SDID string
+
+	// EnterpriseID is appended to SD-IDs, i.e. "flume@32473".  Defaults
+	// to DefaultSyslogEnterpriseID.
+	EnterpriseID string
+}
+
+// SeverityForLevel maps slog levels to syslog severities:
+//
+//   - LevelError+4 and above: SeverityCritical
+//   - LevelError and above: SeverityError
+//   - LevelWarn and above: SeverityWarning
+//   - LevelInfo+2 and above: SeverityNotice
+//   - LevelInfo and above: SeverityInfo
+//   - below LevelInfo: SeverityDebug
+func SeverityForLevel(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError+4:
+		return SeverityCritical
+	case l >= slog.LevelError:
+		return SeverityError
+	case l >= slog.LevelWarn:
+		return SeverityWarning
+	case l >= slog.LevelInfo+2:
+		return SeverityNotice
+	case l >= slog.LevelInfo:
+		return SeverityInfo
+	default:
+		return SeverityDebug
+	}
+}
+
+// NewSyslogHandler returns a slog.Handler which formats records as syslog messages,
+// and writes them to w, one message per call to Write.  To send messages to a syslog
+// server, w should be a *SyslogConn.  opts may be nil.
+//
+// In the RFC 5424 format, the level is mapped to the severity with SeverityForLevel,
+// the LoggerKey attr is mapped to the APP-NAME (or MSGID), and the remaining attrs are
+// mapped to STRUCTURED-DATA elements.  With AddSource, the source is rendered as
+// a "source" parameter, in the form "file:line".
+//
+// Messages are often framed by newlines, so a message never contains CR or LF: they
+// are escaped as `\r` and `\n` in the MSG, the structured data, and the key=value pairs.
+func NewSyslogHandler(w io.Writer, opts *SyslogOptions) slog.Handler {
+	var o SyslogOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Facility == FacilityKern {
+		o.Facility = FacilityUser
+	}
+
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+
+	if o.AppName == "" {
+		o.AppName = filepath.Base(os.Args[0])
+	}
+
+	if o.SDID == "" {
+		o.SDID = "flume"
+	}
+
+	if o.EnterpriseID == "" {
+		o.EnterpriseID = DefaultSyslogEnterpriseID
+	}
+
+	return newObjectHandler(w, &o.HandlerOptions, func(buf *bytes.Buffer, record slog.Record, obj *attrObject) error {
+		o.encode(buf, record, obj)
+		return nil
+	})
+}
+
+// NewSyslogHandlerFn returns a HandlerFn which sends syslog messages to the syslog server
+// at the network address, using a single, shared SyslogConn.  network may be "udp",
+// "tcp", "unix" (a stream socket), or "unixgram".  If network is empty, messages are
+// written to the writer passed to the HandlerFn, i.e. flume's output, terminated by
+// newlines.
+//
+// The Level, AddSource, and ReplaceAttr options passed to the HandlerFn override
+// those in opts.
+func NewSyslogHandlerFn(network, addr string, opts *SyslogOptions) HandlerFn {
+	var conn *SyslogConn
+	if network != "" {
+		conn = NewSyslogConn(network, addr)
+	}
+
+	return func(_ string, w io.Writer, hOpts *slog.HandlerOptions) slog.Handler {
+		var o SyslogOptions
+		if opts != nil {
+			o = *opts
+		}
+
+		if hOpts != nil {
+			o.HandlerOptions = *hOpts
+		}
+
+		if conn != nil {
+			w = conn
+		} else {
+			w = newlineWriter{w}
+		}
+
+		return NewSyslogHandler(w, &o)
+	}
+}
+
+// newlineWriter terminates each Write with a newline.
+type newlineWriter struct {
+	io.Writer
+}
+
+func (w newlineWriter) Write(p []byte) (int, error) {
+	_, err := w.Writer.Write(append(p, '\n'))
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return len(p), nil
+}
+
+func (o *SyslogOptions) encode(buf *bytes.Buffer, record slog.Record, obj *attrObject) {
+	// the built-in fields are rendered in the header, not as structured data
+	msg := record.Message
+	if v, ok := obj.remove(slog.MessageKey); ok {
+		if v, ok := v.(slog.Value); ok {
+			msg = v.String()
+		}
+	}
+
+	msg = syslogNewlineEscaper.Replace(msg)
+
+	obj.remove(slog.TimeKey)
+	obj.remove(slog.LevelKey)
+
+	appName, msgID := o.AppName, ""
+
+	if v, ok := obj.remove(LoggerKey); ok {
+		if v, ok := v.(slog.Value); ok && v.String() != "" {
+			if o.LoggerAsMsgID {
+				msgID = v.String()
+			} else {
+				appName = v.String()
+			}
+		}
+	}
+
+	pri := o.Facility*8 + SeverityForLevel(record.Level)
+
+	if o.Format == RFC3164 {
+		ts := record.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+
+		fmt.Fprintf(buf, "<%d>%s %s %s[%d]: %s",
+			pri,
+			ts.Format(time.Stamp),
+			syslogHeaderField(o.Hostname, 255),
+			syslogHeaderField(appName, 32),
+			os.Getpid(),
+			msg,
+		)
+
+		appendSyslogKeyValues(buf, "", obj)
+
+		return
+	}
+
+	ts := "-"
+	if !record.Time.IsZero() {
+		ts = record.Time.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+
+	fmt.Fprintf(buf, "<%d>1 %s %s %s %d %s ",
+		pri,
+		ts,
+		syslogHeaderField(o.Hostname, 255),
+		syslogHeaderField(appName, 48),
+		os.Getpid(),
+		syslogHeaderField(msgID, 32),
+	)
+
+	o.appendStructuredData(buf, obj)
+
+	if msg != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(msg)
+	}
+}
+
+func (o *SyslogOptions) appendStructuredData(buf *bytes.Buffer, obj *attrObject) {
+	// first, an element for all the non-group attrs, then an element for each group.
+	// An SD-ID must not occur more than once in a message, so groups whose SD-ID is the
+	// same as an earlier element's, like a group named after the SDID option, are
+	// merged into that element.
+	var ids []string
+
+	elements := map[string]*bytes.Buffer{}
+
+	element := func(name string) *bytes.Buffer {
+		id := syslogName(name) + "@" + o.EnterpriseID
+
+		params, ok := elements[id]
+		if !ok {
+			params = &bytes.Buffer{}
+			elements[id] = params
+			ids = append(ids, id)
+		}
+
+		return params
+	}
+
+	for _, k := range obj.keys {
+		if v, ok := obj.vals[k].(slog.Value); ok {
+			appendSyslogParam(element(o.SDID), k, v)
+		}
+	}
+
+	for _, k := range obj.keys {
+		if group, ok := obj.vals[k].(*attrObject); ok {
+			appendSyslogGroupParams(element(k), "", group)
+		}
+	}
+
+	if len(ids) == 0 {
+		buf.WriteByte('-')
+		return
+	}
+
+	for _, id := range ids {
+		buf.WriteString("[" + id)
+		buf.Write(elements[id].Bytes())
+		buf.WriteByte(']')
+	}
+}
+
+func appendSyslogGroupParams(buf *bytes.Buffer, prefix string, obj *attrObject) {
+	for _, k := range obj.keys {
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			appendSyslogGroupParams(buf, prefix+k+".", v)
+		case slog.Value:
+			appendSyslogParam(buf, prefix+k, v)
+		}
+	}
+}
+
+// sdValueEscaper escapes the characters which must be escaped in
+// structured data param values, see RFC 5424 section 6.3.3, and newlines.
+var sdValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`, "\r", `\r`, "\n", `\n`)
+
+// syslogNewlineEscaper escapes newlines, which would split newline framed messages.
+var syslogNewlineEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+
+func appendSyslogParam(buf *bytes.Buffer, name string, v slog.Value) {
+	buf.WriteString(" " + syslogName(name) + `="`)
+	buf.WriteString(sdValueEscaper.Replace(syslogValueString(v)))
+	buf.WriteByte('"')
+}
+
+func appendSyslogKeyValues(buf *bytes.Buffer, prefix string, obj *attrObject) {
+	for _, k := range obj.keys {
+		switch v := obj.vals[k].(type) {
+		case *attrObject:
+			appendSyslogKeyValues(buf, prefix+k+".", v)
+		case slog.Value:
+			s := syslogValueString(v)
+			if strings.ContainsAny(s, " \"=\r\n") {
+				s = strconv.Quote(s)
+			}
+
+			buf.WriteString(" " + syslogNewlineEscaper.Replace(prefix+k) + "=" + s)
+		}
+	}
+}
+
+func syslogValueString(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if src, ok := v.Any().(*slog.Source); ok {
+			return src.File + ":" + strconv.Itoa(src.Line)
+		}
+	default:
+	}
+
+	return v.String()
+}
+
+// syslogHeaderField renders a header field: printable US-ASCII, truncated to
+// maxLen, or "-" if empty.
+func syslogHeaderField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// syslogName renders an SD-NAME: printable US-ASCII, except '=', ' ', ']', and '"',
+// truncated to 32 characters.
+func syslogName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' || r == '@' {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	if len(s) > 32 {
+		s = s[:32]
+	}
+
+	if s == "" {
+		return "_"
+	}
+
+	return s
+}
+
+// ErrSyslogConnClosed is returned when writing to a closed SyslogConn.
+var ErrSyslogConnClosed = errors.New("syslog connection closed")
+
+// SyslogConn is an io.WriteCloser which sends each Write to a syslog server as a
+// single message.  It connects lazily, on the first Write, and if a Write fails,
+// it reconnects and retries once.
+//
+// On "tcp" connections, messages are framed with octet counting, as described in
+// RFC 6587.  On "unix" stream sockets, messages are terminated by newlines, which is
+// what local syslog daemons, like rsyslog and syslog-ng, expect.
+type SyslogConn struct {
+	// DialTimeout is the timeout for establishing connections.  Defaults to 5 seconds.
+	DialTimeout time.Duration
+
+	// WriteTimeout, if set, is the deadline for each message write.
+	WriteTimeout time.Duration
+
+	rc *reconnectingConn
+}
+
+// NewSyslogConn returns a SyslogConn for the server at the network address.  It doesn't
+// connect until the first Write.
+func NewSyslogConn(network, addr string) *SyslogConn {
+	return &SyslogConn{
+		DialTimeout: 5 * time.Second,
+		rc:          newReconnectingConn(network, addr, "syslog at "+network+"://"+addr, ErrSyslogConnClosed),
+	}
+}
+
+// Write sends p as a single syslog message.
+func (c *SyslogConn) Write(p []byte) (int, error) {
+	msg := c.frame(p)
+
+	err := c.rc.write(c.DialTimeout, c.WriteTimeout, func(conn net.Conn) error {
+		_, err := conn.Write(msg)
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// frame converts a message into the bytes to write to the connection.
+func (c *SyslogConn) frame(p []byte) []byte {
+	switch c.rc.network {
+	case "tcp", "tcp4", "tcp6":
+		return append([]byte(strconv.Itoa(len(p))+" "), p...)
+	case "unix":
+		// local syslog daemons expect newline terminated messages on stream sockets
+		return append(p[:len(p):len(p)], '\n')
+	}
+
+	return p
+}
+
+// Close closes the connection.  Subsequent writes will fail.
+func (c *SyslogConn) Close() error {
+	return c.rc.close()
+}
@@ -0,0 +1,336 @@
+package flume
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 20, 41, 28, 515*1e6, time.UTC)
+	pid := os.Getpid()
+
+	tests := []struct {
+		name    string
+		opts    *SyslogOptions
+		level   slog.Level
+		msg     string
+		handler func(h slog.Handler) slog.Handler
+		attrs   []any
+		want    string
+	}{
+		{
+			name: "rfc5424",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")})
+			},
+			attrs: []any{"color", "red", slog.Group("req", "method", "GET", slog.Group("headers", "accept", "*/*"))},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost http %d - [flume@32473 color="red"][req@32473 method="GET" headers.accept="*/*"] hi`, pid),
+		},
+		{
+			name: "no attrs",
+			want: fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d - - hi`, pid),
+		},
+		{
+			name:  "severity and facility",
+			opts:  &SyslogOptions{Facility: FacilityLocal0},
+			level: slog.LevelError,
+			want:  fmt.Sprintf(`<131>1 2024-01-01T20:41:28.515000Z myhost myapp %d - - hi`, pid),
+		},
+		{
+			name: "logger as msgid",
+			opts: &SyslogOptions{LoggerAsMsgID: true, SDID: "app", EnterpriseID: "1234"},
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")})
+			},
+			attrs: []any{"size", 5},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d http [app@1234 size="5"] hi`, pid),
+		},
+		{
+			name:  "escaping",
+			attrs: []any{`a b=c"]`, `quote" back\ bracket]`},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d - [flume@32473 a_b_c__="quote\" back\\ bracket\]"] hi`, pid),
+		},
+		{
+			name:  "newlines",
+			msg:   "line1\r\n<14>1 forged",
+			attrs: []any{"err", "a\nb"},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d - [flume@32473 err="a\nb"] line1\r\n<14>1 forged`, pid),
+		},
+		{
+			name:  "rfc3164 newlines",
+			opts:  &SyslogOptions{Format: RFC3164},
+			msg:   "line1\n<14>forged",
+			attrs: []any{"err", "a\nb", "k\ney", 1},
+			want:  fmt.Sprintf(`<14>Jan  1 20:41:28 myhost myapp[%d]: line1\n<14>forged err="a\nb" k\ney=1`, pid),
+		},
+		{
+			name: "with group",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("req").WithAttrs([]slog.Attr{slog.String("method", "GET")})
+			},
+			attrs: []any{"path", "/"},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d - [req@32473 method="GET" path="/"] hi`, pid),
+		},
+		{
+			name:  "group named after the sd-id",
+			attrs: []any{"color", "red", slog.Group("req", "method", "GET"), slog.Group("flume", "version", "2")},
+			want:  fmt.Sprintf(`<14>1 2024-01-01T20:41:28.515000Z myhost myapp %d - [flume@32473 color="red" version="2"][req@32473 method="GET"] hi`, pid),
+		},
+		{
+			name:  "rfc3164",
+			opts:  &SyslogOptions{Format: RFC3164},
+			level: slog.LevelWarn,
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, "http")})
+			},
+			attrs: []any{"color", "red", "msg2", "a b", slog.Group("req", "method", "GET")},
+			want:  fmt.Sprintf(`<12>Jan  1 20:41:28 myhost http[%d]: hi color=red msg2="a b" req.method=GET`, pid),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SyslogOptions{}
+			if tt.opts != nil {
+				opts = *tt.opts
+			}
+
+			opts.Hostname = "myhost"
+			opts.AppName = "myapp"
+
+			buf := bytes.NewBuffer(nil)
+
+			h := NewSyslogHandler(buf, &opts)
+			if tt.handler != nil {
+				h = tt.handler(h)
+			}
+
+			msg := tt.msg
+			if msg == "" {
+				msg = "hi"
+			}
+
+			rec := slog.NewRecord(fixedTime, tt.level, msg, 0)
+			rec.Add(tt.attrs...)
+
+			require.NoError(t, h.Handle(context.Background(), rec))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestSeverityForLevel(t *testing.T) {
+	tests := map[slog.Level]int{
+		slog.LevelDebug:     SeverityDebug,
+		slog.LevelInfo - 1:  SeverityDebug,
+		slog.LevelInfo:      SeverityInfo,
+		slog.LevelInfo + 2:  SeverityNotice,
+		slog.LevelWarn:      SeverityWarning,
+		slog.LevelError:     SeverityError,
+		slog.LevelError + 4: SeverityCritical,
+	}
+
+	for level, want := range tests {
+		assert.Equal(t, want, SeverityForLevel(level), level.String())
+	}
+}
+
+func TestNewSyslogHandlerFn_writer(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	l := slog.New(NewHandler(buf, &HandlerOptions{HandlerFn: LookupHandlerFn(SyslogHandler)}).Named("http"))
+
+	l.Info("one")
+	l.Info("two")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^<14>1 \S+ \S+ http \d+ - - one$`, lines[0])
+	assert.Regexp(t, `^<14>1 \S+ \S+ http \d+ - - two$`, lines[1])
+
+	buf.Reset()
+	l.Info("line1\nline2")
+
+	assert.Regexp(t, `^<14>1 \S+ \S+ http \d+ - - line1\\nline2\n$`, buf.String())
+}
+
+func TestNewSyslogHandlerFn_udp(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer pc.Close()
+
+	l := slog.New(NewHandler(nil, &HandlerOptions{
+		HandlerFn: NewSyslogHandlerFn("udp", pc.LocalAddr().String(), &SyslogOptions{AppName: "myapp"}),
+	}))
+	l.Warn("hi", "color", "red")
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^<12>1 \S+ \S+ myapp \d+ - \[flume@32473 color="red"\] hi$`, string(b[:n]))
+}
+
+func TestNewSyslogHandlerFn_unixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+
+	pc, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+
+	defer pc.Close()
+
+	l := slog.New(NewHandler(nil, &HandlerOptions{
+		HandlerFn: NewSyslogHandlerFn("unixgram", path, &SyslogOptions{AppName: "myapp"}),
+	}))
+	l.Info("hi")
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^<14>1 \S+ \S+ myapp \d+ - - hi$`, string(b[:n]))
+}
+
+func TestSyslogConn_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go readOctetCounted(c, msgs)
+		}
+	}()
+
+	conn := NewSyslogConn("tcp", ln.Addr().String())
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("first"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", receive(t, msgs))
+
+	// break the connection.  The next write should fail, then reconnect and succeed.
+	conn.rc.mu.Lock()
+	_ = conn.rc.conn.Close()
+	conn.rc.mu.Unlock()
+
+	_, err = conn.Write([]byte("second message"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "second message", receive(t, msgs))
+
+	require.NoError(t, conn.Close())
+
+	_, err = conn.Write([]byte("third"))
+	require.ErrorIs(t, err, ErrSyslogConnClosed)
+}
+
+func TestSyslogConn_unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer c.Close()
+
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			msgs <- scanner.Text()
+		}
+	}()
+
+	conn := NewSyslogConn("unix", path)
+	defer conn.Close()
+
+	for _, msg := range []string{"first", "second message"} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "first", receive(t, msgs), "messages should be newline terminated, not octet counted")
+	assert.Equal(t, "second message", receive(t, msgs))
+}
+
+func TestSyslogConn_dialError(t *testing.T) {
+	conn := NewSyslogConn("unixgram", filepath.Join(t.TempDir(), "missing.sock"))
+
+	_, err := conn.Write([]byte("hi"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "writing to syslog at unixgram://")
+}
+
+func readOctetCounted(c net.Conn, msgs chan<- string) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+
+	for {
+		lenStr, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+		if err != nil {
+			return
+		}
+
+		b := make([]byte, n)
+
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return
+		}
+
+		msgs <- string(b)
+	}
+}
+
+func receive(t *testing.T, msgs <-chan string) string {
+	t.Helper()
+
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for syslog message")
+		return ""
+	}
+}
@@ -0,0 +1,45 @@
+package flume
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// colorEnabled decides whether the term-auto handler should emit ANSI colors
+// when writing to w.
+//
+// The NO_COLOR and FORCE_COLOR conventions take precedence (see https://no-color.org/
+// and https://force-color.org/): if NO_COLOR is set to a non-empty value, color is
+// disabled.  Otherwise, if FORCE_COLOR is set, color is enabled, unless FORCE_COLOR is
+// "0" or "false", which disables it.  Otherwise, color is enabled only if w is a terminal.
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	switch strings.ToLower(os.Getenv("FORCE_COLOR")) {
+	case "":
+	case "0", "false":
+		return false
+	default:
+		return true
+	}
+
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a terminal.  Only writers which expose their file
+// descriptor with an Fd() method, like *os.File, can be detected as terminals.
+// Anything else, like buffers, pipes, regular files, and other character devices like
+// /dev/null, is not a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+
+	return term.IsTerminal(int(f.Fd())) //nolint:gosec
+}
@@ -0,0 +1,85 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		noColor    string
+		forceColor string
+		want       bool
+	}{
+		{name: "defaults", want: false},
+		{name: "no color", noColor: "1", want: false},
+		{name: "force color", forceColor: "1", want: true},
+		{name: "force color true", forceColor: "true", want: true},
+		{name: "force color 0", forceColor: "0", want: false},
+		{name: "force color false", forceColor: "FALSE", want: false},
+		{name: "no color wins", noColor: "1", forceColor: "1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("FORCE_COLOR", tt.forceColor)
+
+			assert.Equal(t, tt.want, colorEnabled(&bytes.Buffer{}))
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+	assert.False(t, isTerminal(io.Discard))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	assert.False(t, isTerminal(f), "regular files are not terminals")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	defer r.Close()
+	defer w.Close()
+
+	assert.False(t, isTerminal(w), "pipes are not terminals")
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+
+	defer devNull.Close()
+
+	assert.False(t, isTerminal(devNull), "/dev/null is not a terminal")
+
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+
+		assert.True(t, isTerminal(tty))
+	}
+}
+
+func TestTermAutoHandler(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "1")
+
+	buf := &bytes.Buffer{}
+	h := NewHandler(buf, &HandlerOptions{HandlerFn: TermAutoHandlerFn()})
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hi", 0)))
+
+	assert.Contains(t, buf.String(), "\x1b[", "FORCE_COLOR should enable color when writing to a buffer")
+}
@@ -0,0 +1,305 @@
+package flume
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidTraceparent is returned when parsing a malformed W3C traceparent header.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext holds the identifiers of a W3C trace context.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// IsValid returns true if the trace and span IDs are both non-zero.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Sampled returns true if the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 != 0
+}
+
+// String returns the trace context in the W3C traceparent header format.
+func (tc TraceContext) String() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" + hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// ParseTraceparent parses a W3C traceparent header, like
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (TraceContext, error) {
+	var tc TraceContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return tc, fmt.Errorf("%w: %q", ErrInvalidTraceparent, s)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// future versions may append fields, but version 00 has exactly four
+	if len(version) != 2 || version == "ff" || (version == "00" && len(parts) != 4) {
+		return tc, fmt.Errorf("%w: unsupported version in %q", ErrInvalidTraceparent, s)
+	}
+
+	if !decodeLowerHex(tc.TraceID[:], traceID) || !decodeLowerHex(tc.SpanID[:], spanID) {
+		return tc, fmt.Errorf("%w: %q", ErrInvalidTraceparent, s)
+	}
+
+	var f [1]byte
+	if !decodeLowerHex(f[:], flags) {
+		return tc, fmt.Errorf("%w: %q", ErrInvalidTraceparent, s)
+	}
+
+	tc.Flags = f[0]
+
+	if !tc.IsValid() {
+		return tc, fmt.Errorf("%w: all zero trace or span id in %q", ErrInvalidTraceparent, s)
+	}
+
+	return tc, nil
+}
+
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx which carries the trace context.  TraceMiddleware
+// adds the trace context to records logged with the returned context.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// ContextWithTraceparent parses a W3C traceparent header, and returns a copy of ctx
+// which carries the trace context.  If the header is invalid, it returns ctx unchanged,
+// and the error.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, error) {
+	tc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx, err
+	}
+
+	return ContextWithTrace(ctx, tc), nil
+}
+
+// TraceFromContext returns the trace context stored in ctx by ContextWithTrace or
+// ContextWithTraceparent.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+
+	return tc, ok && tc.IsValid()
+}
+
+// TraceExtractor extracts a trace context from a context.Context.  Implement it to
+// correlate logs with traces from a tracing library.
+type TraceExtractor interface {
+	TraceFromContext(ctx context.Context) (TraceContext, bool)
+}
+
+// TraceExtractorFn adapts a function to the TraceExtractor interface.  For example,
+// to extract the current OpenTelemetry span:
+//
+//	flume.TraceExtractorFn(func(ctx context.Context) (flume.TraceContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return flume.TraceContext{
+//			TraceID: sc.TraceID(),
+//			SpanID:  sc.SpanID(),
+//			Flags:   byte(sc.TraceFlags()),
+//		}, sc.IsValid()
+//	})
+type TraceExtractorFn func(ctx context.Context) (TraceContext, bool)
+
+// TraceFromContext implements TraceExtractor.
+func (f TraceExtractorFn) TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	return f(ctx)
+}
+
+// TraceKeys are the attr keys TraceMiddleware uses for the trace context.  Empty
+// keys are omitted.
+type TraceKeys struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+}
+
+var (
+	// OTelTraceKeys are the field names of the OpenTelemetry log data model.
+	OTelTraceKeys = TraceKeys{TraceID: "trace_id", SpanID: "span_id", TraceFlags: "trace_flags"}
+	// ECSTraceKeys are the field names of the Elastic Common Schema.
+	ECSTraceKeys = TraceKeys{TraceID: "trace.id", SpanID: "span.id"}
+)
+
+// TraceOptions configures TraceMiddleware.
+type TraceOptions struct {
+	// Keys defaults to OTelTraceKeys.
+	Keys *TraceKeys
+
+	// Extractors are consulted, in order, if the context doesn't carry a trace context
+	// stored by ContextWithTrace or ContextWithTraceparent.  The first valid trace
+	// context is used.
+	Extractors []TraceExtractor
+}
+
+// TraceMiddleware returns middleware which adds the trace context carried by the
+// context passed to Handle to the record.  The trace and span IDs are added as lower-case
+// hex strings, and the trace flags as a two digit hex string, i.e. "01".  opts may be nil.
+//
+// Use the context-aware logging methods, like slog.Logger.InfoContext, to pass the context.
+// Records logged without a trace context are passed through unchanged.
+//
+// The attrs are always added at the root of the record, even if the logger has open
+// groups, since log backends look for them there to correlate logs with traces.  To do
+// that, the middleware holds the groups opened with WithGroup, and the attrs added to
+// them, and nests the record's attrs in them when a record has a trace context.
+func TraceMiddleware(opts *TraceOptions) Middleware {
+	keys := OTelTraceKeys
+
+	var extractors []TraceExtractor
+
+	if opts != nil {
+		if opts.Keys != nil {
+			keys = *opts.Keys
+		}
+
+		extractors = opts.Extractors
+	}
+
+	return MiddlewareFn(func(next slog.Handler) slog.Handler {
+		return &traceHandler{keys: keys, extractors: extractors, next: next, grouped: next}
+	})
+}
+
+type traceHandler struct {
+	keys       TraceKeys
+	extractors []TraceExtractor
+
+	// next is the next handler, with the attrs added before the first group
+	next slog.Handler
+	// grouped is next, with the open groups and their attrs, for records without a trace
+	grouped slog.Handler
+
+	// open groups
+	groups []string
+	// attrs added with WithAttrs to each open group
+	attrs [][]slog.Attr
+}
+
+func (h *traceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	tc, ok := TraceFromContext(ctx)
+
+	for i := 0; !ok && i < len(h.extractors); i++ {
+		tc, ok = h.extractors[i].TraceFromContext(ctx)
+		ok = ok && tc.IsValid()
+	}
+
+	if !ok {
+		return h.grouped.Handle(ctx, record)
+	}
+
+	if len(h.groups) > 0 {
+		var attrs []slog.Attr
+
+		record.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a)
+			return true
+		})
+
+		// nest the attrs in the open groups, innermost first
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			members := append(slices.Clip(h.attrs[i]), attrs...)
+
+			attrs = nil
+			if len(members) > 0 {
+				attrs = []slog.Attr{{Key: h.groups[i], Value: slog.GroupValue(members...)}}
+			}
+		}
+
+		record = slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+		record.AddAttrs(attrs...)
+	} else {
+		record = record.Clone()
+	}
+
+	if h.keys.TraceID != "" {
+		record.AddAttrs(slog.String(h.keys.TraceID, hex.EncodeToString(tc.TraceID[:])))
+	}
+
+	if h.keys.SpanID != "" {
+		record.AddAttrs(slog.String(h.keys.SpanID, hex.EncodeToString(tc.SpanID[:])))
+	}
+
+	if h.keys.TraceFlags != "" {
+		record.AddAttrs(slog.String(h.keys.TraceFlags, hex.EncodeToString([]byte{tc.Flags})))
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.grouped = h.grouped.WithAttrs(attrs)
+
+	if len(h2.groups) == 0 {
+		h2.next = h2.grouped
+	} else {
+		last := len(h2.groups) - 1
+		h2.attrs[last] = append(slices.Clip(h2.attrs[last]), attrs...)
+	}
+
+	return h2
+}
+
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.grouped = h.grouped.WithGroup(name)
+	h2.groups = append(h2.groups, name)
+	h2.attrs = append(h2.attrs, nil)
+
+	return h2
+}
+
+func (h *traceHandler) Flush(ctx context.Context) error {
+	return flushHandler(ctx, h.grouped)
+}
+
+func (h *traceHandler) clone() *traceHandler {
+	h2 := *h
+	h2.groups = slices.Clip(h.groups)
+	h2.attrs = slices.Clone(h.attrs)
+
+	return &h2
+}
@@ -0,0 +1,165 @@
+package flume
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+
+	assert.Equal(t, [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, tc.TraceID)
+	assert.Equal(t, [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}, tc.SpanID)
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, testTraceparent, tc.String())
+
+	// future versions may have additional fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	require.NoError(t, err)
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bx-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	}
+
+	for _, s := range invalid {
+		_, err := ParseTraceparent(s)
+		require.ErrorIs(t, err, ErrInvalidTraceparent, s)
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	tc, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+
+	otherTC := tc
+	otherTC.SpanID = [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	otherTC.Flags = 0
+
+	ctxWithTrace, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		opts *TraceOptions
+		ctx  context.Context //nolint:containedctx
+		want string
+	}{
+		{
+			name: "no trace",
+			ctx:  context.Background(),
+			want: "level=INFO msg=hi\n",
+		},
+		{
+			name: "traceparent",
+			ctx:  ctxWithTrace,
+			want: "level=INFO msg=hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01\n",
+		},
+		{
+			name: "ecs keys",
+			opts: &TraceOptions{Keys: &ECSTraceKeys},
+			ctx:  ctxWithTrace,
+			want: "level=INFO msg=hi trace.id=4bf92f3577b34da6a3ce929d0e0e4736 span.id=00f067aa0ba902b7\n",
+		},
+		{
+			name: "custom keys",
+			opts: &TraceOptions{Keys: &TraceKeys{TraceID: "traceID"}},
+			ctx:  ctxWithTrace,
+			want: "level=INFO msg=hi traceID=4bf92f3577b34da6a3ce929d0e0e4736\n",
+		},
+		{
+			name: "extractor",
+			opts: &TraceOptions{Extractors: []TraceExtractor{
+				TraceExtractorFn(func(context.Context) (TraceContext, bool) { return TraceContext{}, true }),
+				TraceExtractorFn(func(context.Context) (TraceContext, bool) { return otherTC, true }),
+			}},
+			ctx:  context.Background(),
+			want: "level=INFO msg=hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=0102030405060708 trace_flags=00\n",
+		},
+		{
+			name: "context takes precedence over extractors",
+			opts: &TraceOptions{Extractors: []TraceExtractor{
+				TraceExtractorFn(func(context.Context) (TraceContext, bool) { return otherTC, true }),
+			}},
+			ctx:  ctxWithTrace,
+			want: "level=INFO msg=hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			h := NewHandler(buf, &HandlerOptions{
+				ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+				Middleware:   []Middleware{TraceMiddleware(tt.opts)},
+			})
+
+			slog.New(h).InfoContext(tt.ctx, "hi")
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTraceMiddleware_groups(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	h := NewHandler(buf, &HandlerOptions{
+		HandlerFn:    JSONHandlerFn(),
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{removeKeys(slog.TimeKey)},
+		Middleware:   []Middleware{TraceMiddleware(nil)},
+	})
+
+	l := slog.New(h).With("app", "api").WithGroup("req").With("method", "GET").WithGroup("user")
+
+	l.InfoContext(ctx, "hi", "id", 5)
+	assert.JSONEq(t, `{
+		"level":"INFO","msg":"hi","app":"api",
+		"req":{"method":"GET","user":{"id":5}},
+		"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"
+	}`, buf.String(), "trace attrs should be at the root, not in the open groups")
+
+	buf.Reset()
+
+	l.InfoContext(ctx, "hi")
+	assert.JSONEq(t, `{
+		"level":"INFO","msg":"hi","app":"api","req":{"method":"GET"},
+		"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"
+	}`, buf.String(), "empty groups should be omitted")
+
+	buf.Reset()
+
+	l.Info("hi", "id", 5)
+	assert.JSONEq(t, `{"level":"INFO","msg":"hi","app":"api","req":{"method":"GET","user":{"id":5}}}`, buf.String())
+}
+
+func TestContextWithTraceparent(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), "garbage")
+	require.ErrorIs(t, err, ErrInvalidTraceparent)
+
+	_, ok := TraceFromContext(ctx)
+	assert.False(t, ok)
+
+	ctx = ContextWithTrace(context.Background(), TraceContext{})
+
+	_, ok = TraceFromContext(ctx)
+	assert.False(t, ok, "invalid trace contexts should be ignored")
+}